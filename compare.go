@@ -0,0 +1,162 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"queue_fairness/sim"
+)
+
+var (
+	flagQueueTypes = flag.String("queue-types", strings.Join(sim.TaskQueueTypes, ","), "the task queue types to run (compare only)")
+	flagBaseline   = flag.String("baseline", "simple", "the task queue type deltas are relative to (compare only)")
+)
+
+// compare runs the same scenario and seed, and so the same task arrivals, against each of
+// the task queue types and prints their queueing delay percentiles side by side.
+func compare() {
+	var queueTypes []string
+	for _, queueType := range strings.Split(*flagQueueTypes, ",") {
+		if queueType = strings.TrimSpace(queueType); queueType != "" {
+			queueTypes = append(queueTypes, queueType)
+		}
+	}
+	baseline := slices.Index(queueTypes, *flagBaseline)
+	if baseline < 0 {
+		fmt.Fprintf(os.Stderr, "baseline %q must be one of the queue types: %v\n", *flagBaseline, strings.Join(queueTypes, ", "))
+		os.Exit(1)
+	}
+
+	scenarios := make([]sim.Scenario, len(queueTypes))
+	for index, queueType := range queueTypes {
+		scenario, err := loadScenario(queueType)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", queueType, err)
+			os.Exit(1)
+		}
+		scenarios[index] = scenario
+	}
+	seed := *scenarios[0].Seed
+
+	sim.LogOutput = io.Discard
+	results := make([]sim.SimulationResults, len(queueTypes))
+	for index, scenario := range scenarios {
+		scenario.Seed = &seed
+		s, err := scenario.NewSimulation(sim.NewSimulatedClock(sim.SimulatedStartTime))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", queueTypes[index], err)
+			os.Exit(1)
+		}
+		if index == 0 {
+			tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			for _, setting := range describeScenario(scenario, s) {
+				if setting.Name == "task queue type" {
+					continue
+				}
+				fmt.Fprintf(tw, "using %s:\t%s\n", setting.Name, setting.Value)
+			}
+			fmt.Fprintf(tw, "using queue types:\t%s\n", strings.Join(queueTypes, ", "))
+			fmt.Fprintf(tw, "using baseline:\t%s\n", queueTypes[baseline])
+			_ = tw.Flush()
+			fmt.Println()
+		}
+		runStart := time.Now()
+		results[index] = s.Simulate()
+		fmt.Printf("simulated %q in %v\n", queueTypes[index], time.Since(runStart).Round(time.Millisecond))
+	}
+	fmt.Println()
+	printComparison(os.Stdout, queueTypes, baseline, results)
+}
+
+// comparisonRow is a row of the comparison tables, e.g. a priority or a fairness key.
+type comparisonRow struct {
+	Label  string
+	Queued func(sim.SimulationResults) (p50, p95, p99 time.Duration)
+}
+
+func printComparison(w io.Writer, queueTypes []string, baseline int, results []sim.SimulationResults) {
+	rows := []comparisonRow{{
+		Label: "all",
+		Queued: func(res sim.SimulationResults) (time.Duration, time.Duration, time.Duration) {
+			return res.QueuedP50, res.QueuedP95, res.QueuedP99
+		},
+	}}
+	for _, p := range []sim.Priority{sim.P0, sim.P1, sim.P2, sim.P3, sim.P4} {
+		if !slices.ContainsFunc(results, func(res sim.SimulationResults) bool { return res.CountByPriority[p] > 0 }) {
+			continue
+		}
+		rows = append(rows, comparisonRow{
+			Label: fmt.Sprintf("priority %q", p),
+			Queued: func(res sim.SimulationResults) (time.Duration, time.Duration, time.Duration) {
+				return res.QueuedP50ByPriority[p], res.QueuedP95ByPriority[p], res.QueuedP99ByPriority[p]
+			},
+		})
+	}
+	fairnessKeys := make(map[string]struct{})
+	for _, res := range results {
+		for key := range res.QueuedP95ByFairnessKey {
+			fairnessKeys[key] = struct{}{}
+		}
+	}
+	for _, key := range sortedKeys(fairnessKeys) {
+		rows = append(rows, comparisonRow{
+			Label: fmt.Sprintf("fairness key %q", key),
+			Queued: func(res sim.SimulationResults) (time.Duration, time.Duration, time.Duration) {
+				return res.QueuedP50ByFairnessKey[key], res.QueuedP95ByFairnessKey[key], res.QueuedP99ByFairnessKey[key]
+			},
+		})
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(tw, "tasks processed\t%s\t\n", strings.Join(queueTypes, "\t"))
+	fmt.Fprint(tw, "\t")
+	for index, res := range results {
+		fmt.Fprintf(tw, "%d%s\t", res.TasksProcessed, formatDelta(float64(res.TasksProcessed), float64(results[baseline].TasksProcessed), index == baseline, nil))
+	}
+	fmt.Fprintln(tw)
+	for percentile, name := range []string{"p50", "p95", "p99"} {
+		fmt.Fprintln(tw, "\t")
+		fmt.Fprintf(tw, "queued %s\t%s\t\n", name, strings.Join(queueTypes, "\t"))
+		for _, row := range rows {
+			fmt.Fprintf(tw, "%s\t", row.Label)
+			baselineQueued := queuedPercentile(row, results[baseline], percentile)
+			for index, res := range results {
+				queued := queuedPercentile(row, res, percentile)
+				fmt.Fprintf(tw, "%v%s\t", queued, formatDelta(float64(queued), float64(baselineQueued), index == baseline, func(delta float64) string {
+					return time.Duration(delta).String()
+				}))
+			}
+			fmt.Fprintln(tw)
+		}
+	}
+	_ = tw.Flush()
+}
+
+func queuedPercentile(row comparisonRow, res sim.SimulationResults, percentile int) time.Duration {
+	p50, p95, p99 := row.Queued(res)
+	queued := []time.Duration{p50, p95, p99}[percentile]
+	return queued.Round(time.Millisecond)
+}
+
+// formatDelta formats the change of a value relative to the baseline's value, as a percentage
+// or, if the baseline's value is zero, as an absolute difference.
+func formatDelta(value, baselineValue float64, isBaseline bool, formatAbsolute func(float64) string) string {
+	switch {
+	case isBaseline:
+		return ""
+	case value == baselineValue:
+		return " (=)"
+	case baselineValue == 0 && formatAbsolute != nil:
+		return fmt.Sprintf(" (+%s)", formatAbsolute(value))
+	case baselineValue == 0:
+		return fmt.Sprintf(" (+%v)", value)
+	default:
+		return fmt.Sprintf(" (%+.1f%%)", 100*(value-baselineValue)/baselineValue)
+	}
+}
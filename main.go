@@ -3,10 +3,14 @@ package main
 import (
 	"flag"
 	"fmt"
+	"io"
 	"math/rand/v2"
 	"os"
 	"runtime/pprof"
 	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
 	"time"
 
 	"queue_fairness/sim"
@@ -15,83 +19,99 @@ import (
 var (
 	flagRealTime   = flag.Bool("real-time", false, "if we should simulate using real (wall clock) time")
 	flagCPUProfile = flag.Bool("cpu-profile", false, "if we should take a cpu profile")
-	flagQueueType  = flag.String("queue-type", "feeder", "which queue type to use (simple|priority|fairness|feeder)")
+	flagConfig     = flag.String("config", "", "a json scenario file to run, in place of the scenario flags below")
+	flagSeed       = flag.Uint64("seed", 0, "the random seed, such that runs with the same seed and scenario have identical results (defaults to the scenario's seed, or a random seed)")
+
+	flagOutputFormat = flag.String("output-format", "text", "the results output format (text|json|csv)")
+	flagOutputFile   = flag.String("output-file", "", "the file to write results to (defaults to stdout)")
+	flagReport       = flag.String("report", "", "the file to write an html report of the run to, e.g. report.html")
+	flagPercentiles  = flag.String("percentiles", "50,90,99,99.9", "the percentiles to report latencies at")
+
+	flagEngine    = flag.String("engine", "tick", "which simulation engine to use (tick|event)")
+	flagQueueType = flag.String("queue-type", "feeder", "which queue type to use (simple|priority|fairness|feeder|drr|wfq|edf|hfq)")
+	flagCostModel = flag.String("cost-model", "count", "how fair queues charge fairness keys for tasks (count|worker-time)")
+
+	flagConcurrencyLimit = flag.Int("concurrency-limit", 0, "the most tasks per fairness key held by workers at once (0 is unlimited)")
+
+	flagAgingThresholds = flag.String("aging-thresholds", "", "the queued times at which tasks are raised one priority each, e.g. 5m,10m (priority and fairness queues)")
+	flagAgingInterval   = flag.Duration("aging-interval", 0, "raise tasks one priority for every interval they are queued (priority and fairness queues)")
+
+	flagPriorityClassWeights = flag.String("priority-class-weights", "", "weights that priorities share dispatches by instead of strict priority, e.g. P0:50,P1:25,P2:15,P3:7,P4:3 (fairness queue)")
+	flagPathWeights          = flag.String("path-weights", "", "weights of the paths of hierarchical fairness keys, e.g. acct1:2,acct1/merchant-00:3 (hfq queue)")
 
 	flagDuration                 = flag.Duration("duration", sim.SimulationConfig{}.DurationOrDefault(), "the simulation duration")
 	flagResultsBucketingInterval = flag.Duration("results-bucketing-interval", sim.SimulationConfig{}.ResultsBucketingIntervalOrDefault(), "the results bucketing interval")
 	flagTickInterval             = flag.Duration("tick-interval", sim.SimulationConfig{}.TickIntervalOrDefault(), "the simulation tick interval")
 
+	flagTasksPerSecond = flag.Int("tasks-per-second", sim.SimulationConfig{}.TasksPerSecondOrDefault(), "the mean task arrival rate")
+	flagArrivals       = flag.String("arrivals", "normal", "the task arrival process (normal|poisson|constant|bursty|diurnal)")
+	flagTenants        = flag.String("tenants", "default", "the tenant workload profiles (default|noisy|hierarchical)")
+
+	flagDeadlines  = flag.String("deadlines", "", "how soon tasks of each priority should be dispatched, e.g. P0:5s,P1:30s; results report the deadline miss rate")
 	flagTaskMean   = flag.Duration("task-mean", sim.SimulationConfig{}.TaskDurationMeanOrDefault(), "the task duration mean")
 	flagTaskStdDev = flag.Duration("task-std-dev", sim.SimulationConfig{}.TaskDurationStdDevOrDefault(), "the task duration std dev")
+	flagTaskDist   = flag.String("task-duration", "normal", "the task duration distribution (normal|lognormal|exponential|pareto|bimodal)")
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "compare":
+			_ = flag.CommandLine.Parse(os.Args[2:])
+			compare()
+			return
+		case "sweep":
+			_ = flag.CommandLine.Parse(os.Args[2:])
+			sweep()
+			return
+		}
+	}
 	flag.Parse()
-	s := new(sim.Simulation)
-
-	s.Config.Duration = *flagDuration
-	s.Config.ResultsBucketingInterval = *flagResultsBucketingInterval
-	s.Config.TickInterval = *flagTickInterval
-	s.Config.TaskDurationMean = *flagTaskMean
-	s.Config.TaskDurationStdDev = *flagTaskStdDev
 
-	s.Config.PriorityWeights = map[sim.Priority]int{
-		sim.P0: 100,
-		sim.P1: 200,
-		sim.P2: 1000,
-		sim.P3: 400,
-		sim.P4: 200,
-	}
-	s.Config.FairnessKeyWeights = map[string]int{
-		"high":   100,
-		"medium": 1000,
-		"low":    500,
+	writeResults, err := resultsWriter(*flagOutputFormat)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
 	}
-	s.Config.FairnessWeights = map[string]float64{
-		"high":   70.0,
-		"medium": 20.0,
-		"low":    10.0,
+	// keep stdout clean for machine readable results.
+	var info io.Writer = os.Stdout
+	if *flagOutputFormat != "text" {
+		info = os.Stderr
+		sim.LogOutput = os.Stderr
 	}
 
-	s.RandSource = rand.NewPCG(rand.Uint64(), rand.Uint64())
+	scenario, err := loadScenario("")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
 
+	var clock sim.Clock
 	if *flagRealTime {
-		s.Clock = new(sim.WallClock)
+		clock = new(sim.WallClock)
 	} else {
-		s.Clock = sim.NewSimulatedClock(time.Now())
-	}
-
-	switch *flagQueueType {
-	case "simple":
-		s.TaskQueue = sim.NewSimpleTaskQueue()
-	case "priority":
-		s.TaskQueue = sim.NewPrioritySortedTaskQueue()
-	case "fairness":
-		s.TaskQueue = sim.NewPriorityFairnessTaskQueue(rand.New(s.RandSource))
-	case "feeder":
-		s.TaskQueue = sim.NewFeederTaskQueue(rand.New(s.RandSource), s.Clock, map[string]sim.Limit{
-			"high":   {Actions: 7000, Quantum: time.Second}, // these mirror 70/20/10 for the fk weights
-			"medium": {Actions: 2000, Quantum: time.Second},
-			"low":    {Actions: 1000, Quantum: time.Second},
-		})
-	default:
-		fmt.Fprintf(os.Stderr, "invalid queue type: %v\n", *flagQueueType)
+		clock = sim.NewSimulatedClock(sim.SimulatedStartTime)
+	}
+	s, err := scenario.NewSimulation(clock)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
 		os.Exit(1)
 	}
 
-	fmt.Printf("using task queue type:\t\t%v\n", *flagQueueType)
-	fmt.Printf("using simulation duration:\t%v\n", s.Config.DurationOrDefault())
-	fmt.Printf("using results bucketing interval:\t%v\n", s.Config.ResultsBucketingIntervalOrDefault())
-	fmt.Printf("using tick interval:\t\t%v\n", s.Config.TickIntervalOrDefault())
-	fmt.Printf("using tasks-per-second:\t\t%v\n", s.Config.TasksPerSecondOrDefault())
-	fmt.Printf("using tasks duration mean:\t\t%v\n", s.Config.TaskDurationMeanOrDefault())
-	fmt.Printf("using tasks duration std dev:\t\t%v\n", s.Config.TaskDurationStdDevOrDefault())
-	fmt.Println()
+	settings := describeScenario(scenario, s)
+	tw := tabwriter.NewWriter(info, 0, 0, 2, ' ', 0)
+	for _, setting := range settings {
+		fmt.Fprintf(tw, "using %s:\t%s\n", setting.Name, setting.Value)
+	}
+	_ = tw.Flush()
+	fmt.Fprintln(info)
 
-	s.Init()
+	if *flagRuns > 1 {
+		runMany(info, scenario)
+		return
+	}
 
 	var profileDone func()
-	var err error
 	if *flagCPUProfile {
 		profileDone, err = cpuProfile()
 		if err != nil {
@@ -107,28 +127,588 @@ func main() {
 		profileDone()
 	}
 
-	fmt.Println()
-	fmt.Printf("simulation complete! %v elapsed\n", time.Since(start).Round(time.Millisecond).String())
-	fmt.Println()
-	fmt.Printf("tasks processed: %d\n", res.TasksProcessed)
-	fmt.Printf("queued for \tp95: %v\tavg: %v\n", res.QueuedP95.Round(time.Millisecond).String(), res.QueuedAvg.Round(time.Millisecond).String())
-	fmt.Println()
+	fmt.Fprintln(info)
+	fmt.Fprintf(info, "simulation complete! %v elapsed\n", time.Since(start).Round(time.Millisecond).String())
+	fmt.Fprintln(info)
+
+	output := os.Stdout
+	if *flagOutputFile != "" {
+		output, err = os.Create(*flagOutputFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+	}
+	if err = writeResults(output, res); err == nil && output != os.Stdout {
+		err = output.Close()
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error writing results: %v\n", err)
+		os.Exit(1)
+	}
+
+	if *flagReport != "" {
+		report := sim.Report{
+			Title:    "queue fairness simulation",
+			Settings: settings,
+			Results:  res,
+		}
+		if err = writeReport(*flagReport, report); err != nil {
+			fmt.Fprintf(os.Stderr, "error writing report: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Fprintf(info, "wrote report to %s\n", *flagReport)
+	}
+}
+
+// describeScenario returns the settings of the scenario as shown before a run and in reports.
+func describeScenario(scenario sim.Scenario, s *sim.Simulation) (settings []sim.ReportSetting) {
+	add := func(name string, value any) {
+		settings = append(settings, sim.ReportSetting{Name: name, Value: fmt.Sprint(value)})
+	}
+	if *flagConfig != "" {
+		add("config", *flagConfig)
+	}
+	add("engine", s.Config.Engine)
+	if scenario.Seed != nil {
+		add("seed", *scenario.Seed)
+	}
+	add("task queue type", scenario.Queue.Type)
+	costModel, _ := sim.ParseCostModel(scenario.Queue.CostModel)
+	add("cost model", costModel)
+	if scenario.Queue.ConcurrencyLimit > 0 {
+		add("concurrency limit", scenario.Queue.ConcurrencyLimit)
+	}
+	if aging, err := scenario.Queue.Aging.AgingPolicy("queue.aging"); err == nil && !aging.IsZero() {
+		add("aging", aging)
+	}
+	if len(scenario.Deadlines) > 0 {
+		var deadlines []string
+		for _, p := range sortedKeys(scenario.Deadlines) {
+			deadlines = append(deadlines, p+":"+scenario.Deadlines[p])
+		}
+		add("deadlines", strings.Join(deadlines, ","))
+	}
+	if len(scenario.Queue.PriorityClassWeights) > 0 {
+		var weights []string
+		for _, p := range sortedKeys(scenario.Queue.PriorityClassWeights) {
+			weights = append(weights, fmt.Sprintf("%s:%d", p, scenario.Queue.PriorityClassWeights[p]))
+		}
+		add("priority class weights", strings.Join(weights, ","))
+	}
+	if len(scenario.Queue.PathWeights) > 0 {
+		var weights []string
+		for _, path := range sortedKeys(scenario.Queue.PathWeights) {
+			weights = append(weights, fmt.Sprintf("%s:%v", path, scenario.Queue.PathWeights[path]))
+		}
+		add("path weights", strings.Join(weights, ","))
+	}
+	add("simulation duration", s.Config.DurationOrDefault())
+	add("results bucketing interval", s.Config.ResultsBucketingIntervalOrDefault())
+	add("tick interval", s.Config.TickIntervalOrDefault())
+	add("worker count", s.Config.WorkerCountOrDefault())
+	add("worker task slots", s.Config.WorkerTaskSlotsOrDefault())
+	add("tasks-per-second", s.Config.TasksPerSecondOrDefault())
+	if *flagConfig == "" {
+		add("arrival process", *flagArrivals)
+		add("tenants", *flagTenants)
+		add("tasks duration mean", *flagTaskMean)
+		add("tasks duration std dev", *flagTaskStdDev)
+		add("tasks duration distribution", *flagTaskDist)
+	}
+	for _, phase := range s.Config.PhasesOrDefault() {
+		add("phase "+phase.Name, fmt.Sprintf("from %v", phase.Start))
+	}
+	return
+}
+
+func writeReport(path string, report sim.Report) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err = report.WriteHTML(f); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// resultsWriter returns the function that writes results in a given output format.
+func resultsWriter(format string) (func(io.Writer, sim.SimulationResults) error, error) {
+	switch format {
+	case "text":
+		return printResults, nil
+	case "json":
+		return sim.WriteResultsJSON, nil
+	case "csv":
+		return sim.WriteResultsCSV, nil
+	default:
+		return nil, fmt.Errorf("invalid output format: %v", format)
+	}
+}
+
+// printResults writes the results as human readable text.
+func printResults(w io.Writer, res sim.SimulationResults) error {
+	fmt.Fprintf(w, "tasks processed: %d\n", res.TasksProcessed)
+	fmt.Fprintln(w)
+	printLatencies(w, "latency", res.Percentiles, []latencyRow{
+		{"queued", res.Latencies.Queued},
+		{"service", res.Latencies.Service},
+		{"end-to-end", res.Latencies.EndToEnd},
+	})
+	for _, latency := range []struct {
+		name string
+		of   func(sim.Latencies) sim.LatencyDistribution
+	}{
+		{"end-to-end", func(l sim.Latencies) sim.LatencyDistribution { return l.EndToEnd }},
+		{"queued", func(l sim.Latencies) sim.LatencyDistribution { return l.Queued }},
+	} {
+		var priorityRows, fairnessKeyRows []latencyRow
+		for _, p := range []sim.Priority{sim.P0, sim.P1, sim.P2, sim.P3, sim.P4} {
+			if latencies, ok := res.LatenciesByPriority[p]; ok {
+				priorityRows = append(priorityRows, latencyRow{p.String(), latency.of(latencies)})
+			}
+		}
+		for _, key := range sortedKeys(res.LatenciesByFairnessKey) {
+			fairnessKeyRows = append(fairnessKeyRows, latencyRow{strconv.Quote(key), latency.of(res.LatenciesByFairnessKey[key])})
+		}
+		fmt.Fprintln(w)
+		printLatencies(w, latency.name+" by priority", res.Percentiles, priorityRows)
+		fmt.Fprintln(w)
+		printLatencies(w, latency.name+" by fairness key", res.Percentiles, fairnessKeyRows)
+	}
+	fmt.Fprintln(w)
+	var totalWorkerTime time.Duration
+	for _, workerTime := range res.WorkerTimeByFairnessKey {
+		totalWorkerTime += workerTime
+	}
+	for _, key := range sortedKeys(res.WorkerTimeByFairnessKey) {
+		fmt.Fprintf(w, "worker time by fairness key %q\t%v\t(%.1f%%)\n",
+			key,
+			res.WorkerTimeByFairnessKey[key].Round(time.Second).String(),
+			100*float64(res.WorkerTimeByFairnessKey[key])/float64(totalWorkerTime),
+		)
+	}
 	for _, p := range []sim.Priority{sim.P0, sim.P1, sim.P2, sim.P3, sim.P4} {
-		fmt.Printf("queued for by priority %q [%d]\t\tp95: %v\tavg: %v\n",
-			p,
-			res.CountByPriority[p],
-			res.QueuedP95ByPriority[p].Round(time.Millisecond).String(),
-			res.QueuedAvgByPriority[p].Round(time.Millisecond).String(),
+		if boosted := res.BoostedByPriority[p]; boosted > 0 {
+			fmt.Fprintf(w, "boosted by aging %q\t%d\t(%.1f%%)\n", p.String(), boosted, 100*float64(boosted)/float64(res.CountByPriority[p]))
+		}
+	}
+	for _, key := range sortedKeys(res.ConcurrencyMaxByFairnessKey) {
+		fmt.Fprintf(w, "concurrency by fairness key %q\tmax: %d\tavg: %.1f\n",
+			key,
+			res.ConcurrencyMaxByFairnessKey[key],
+			res.ConcurrencyAvgByFairnessKey[key],
 		)
 	}
-	for _, key := range sortedKeys(res.QueuedP95ByFairnessKey) {
-		fmt.Printf("queued for by fairness key %q [%d]\tp95: %v\tavg: %v\n",
+	fmt.Fprintln(w)
+	printFairness(w, res.Fairness)
+	if len(res.LatenciesByFairnessPath) > 0 {
+		fmt.Fprintln(w)
+		printFairnessPaths(w, res)
+	}
+	if res.Deadlines.Tasks > 0 {
+		fmt.Fprintln(w)
+		printDeadlines(w, res)
+	}
+	fmt.Fprintln(w)
+	printBuckets(w, res)
+	for _, phase := range res.Phases {
+		fmt.Fprintln(w)
+		fmt.Fprintf(w, "phase %q (%v - %v) tql at end: %d\n", phase.Name, phase.Start, phase.End, phase.QueueLength)
+		fmt.Fprintf(w, "queued for \tp95: %v\tavg: %v\n", phase.Results.QueuedP95.Round(time.Millisecond).String(), phase.Results.QueuedAvg.Round(time.Millisecond).String())
+		for _, key := range sortedKeys(phase.Results.QueuedP95ByFairnessKey) {
+			fmt.Fprintf(w, "queued for by fairness key %q [%d]\tp95: %v\tavg: %v\n",
+				key,
+				phase.Results.CountByFairnessKey[key],
+				phase.Results.QueuedP95ByFairnessKey[key].Round(time.Millisecond).String(),
+				phase.Results.QueuedAvgByFairnessKey[key].Round(time.Millisecond).String(),
+			)
+		}
+	}
+	return nil
+}
+
+// printFairness writes Jain's index and each fairness key's share of the tasks processed
+// against its configured share, and how long it was starved for at most.
+func printFairness(w io.Writer, fairness sim.FairnessResults) {
+	fmt.Fprintf(w, "jain index: %.3f\tmax share deviation: %.1f%%\tlongest starvation: %v", fairness.JainIndex, 100*fairness.MaxShareDeviation, fairness.LongestStarvation.Round(time.Millisecond))
+	if fairness.LongestStarvationFairnessKey != "" {
+		fmt.Fprintf(w, " (%q)", fairness.LongestStarvationFairnessKey)
+	}
+	fmt.Fprintln(w)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "fairness key\tshare\tconfigured\tdeviation\tlongest starvation\t")
+	for _, key := range sortedKeys(fairness.ConfiguredShareByFairnessKey) {
+		fmt.Fprintf(tw, "%q\t%.1f%%\t%.1f%%\t%+.1f%%\t%v\t\n",
 			key,
-			res.CountByFairnessKey[key],
-			res.QueuedP95ByFairnessKey[key].Round(time.Millisecond).String(),
-			res.QueuedAvgByFairnessKey[key].Round(time.Millisecond).String(),
+			100*fairness.ShareByFairnessKey[key],
+			100*fairness.ConfiguredShareByFairnessKey[key],
+			100*fairness.ShareDeviation(key),
+			fairness.LongestStarvationByFairnessKey[key].Round(time.Millisecond),
+		)
+	}
+	_ = tw.Flush()
+}
+
+// printFairnessPaths writes the tasks processed and worker time under each parent path of hierarchical fairness keys.
+func printFairnessPaths(w io.Writer, res sim.SimulationResults) {
+	var totalWorkerTime time.Duration
+	for _, workerTime := range res.WorkerTimeByFairnessKey {
+		totalWorkerTime += workerTime
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "fairness path\tcount\tshare\tworker time\tqueued avg\tqueued max\t")
+	for _, path := range sortedKeys(res.LatenciesByFairnessPath) {
+		latencies := res.LatenciesByFairnessPath[path]
+		workerTime := res.WorkerTimeByFairnessPath[path]
+		fmt.Fprintf(tw, "%q\t%d\t%.1f%%\t%v (%.1f%%)\t%v\t%v\t\n",
+			path,
+			res.CountByFairnessPath[path],
+			100*res.Fairness.ShareByFairnessPath[path],
+			workerTime.Round(time.Second),
+			100*float64(workerTime)/float64(max(totalWorkerTime, 1)),
+			latencies.Queued.Avg.Round(time.Millisecond),
+			latencies.Queued.Max.Round(time.Millisecond),
 		)
 	}
+	_ = tw.Flush()
+}
+
+// printDeadlines writes how many tasks with a deadline missed it, overall and by priority and fairness key.
+func printDeadlines(w io.Writer, res sim.SimulationResults) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "deadlines\ttasks\tmissed\tmiss rate\t")
+	printRow := func(name string, deadlines sim.DeadlineResults) {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%.2f%%\t\n", name, deadlines.Tasks, deadlines.Missed, 100*deadlines.MissRate())
+	}
+	printRow("all", res.Deadlines)
+	for _, p := range []sim.Priority{sim.P0, sim.P1, sim.P2, sim.P3, sim.P4} {
+		if deadlines, ok := res.DeadlinesByPriority[p]; ok {
+			printRow(p.String(), deadlines)
+		}
+	}
+	for _, key := range sortedKeys(res.DeadlinesByFairnessKey) {
+		printRow(strconv.Quote(key), res.DeadlinesByFairnessKey[key])
+	}
+	_ = tw.Flush()
+}
+
+// latencyRow is a named latency distribution, e.g. of a priority.
+type latencyRow struct {
+	Name    string
+	Latency sim.LatencyDistribution
+}
+
+// printLatencies writes latency distributions as a table with a column per percentile.
+func printLatencies(w io.Writer, title string, percentiles []float64, rows []latencyRow) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(tw, "%s\tcount\tavg\t", title)
+	for _, percentile := range percentiles {
+		fmt.Fprintf(tw, "%s\t", sim.FormatPercentile(percentile))
+	}
+	fmt.Fprintln(tw, "max\t")
+	for _, row := range rows {
+		fmt.Fprintf(tw, "%s\t%d\t%v\t", row.Name, row.Latency.Count, row.Latency.Avg.Round(time.Millisecond))
+		for _, percentile := range percentiles {
+			value, _ := row.Latency.Percentile(percentile)
+			fmt.Fprintf(tw, "%v\t", value.Round(time.Millisecond))
+		}
+		fmt.Fprintf(tw, "%v\t\n", row.Latency.Max.Round(time.Millisecond))
+	}
+	_ = tw.Flush()
+}
+
+// loadScenario returns the scenario from the config file or from the command line flags,
+// optionally with the task queue type overridden.
+//
+// The scenario's seed is always set, from the --seed flag, the config file or at random.
+func loadScenario(queueType string) (scenario sim.Scenario, err error) {
+	if *flagConfig == "" {
+		if queueType == "" {
+			queueType = *flagQueueType
+		}
+		scenario, err = scenarioFromFlags(queueType)
+	} else {
+		scenario, err = sim.LoadScenario(*flagConfig)
+		if err == nil && queueType != "" {
+			scenario.Queue.Type = queueType
+			if queueType != "feeder" {
+				scenario.Queue.RateLimits = nil
+			}
+			err = scenario.Validate()
+		}
+	}
+	if err != nil {
+		return
+	}
+	if *flagConfig == "" || isFlagSet("percentiles") {
+		if scenario.Percentiles, err = sim.ParsePercentiles(*flagPercentiles); err != nil {
+			return
+		}
+	}
+	if isFlagSet("seed") {
+		scenario.Seed = flagSeed
+	} else if scenario.Seed == nil {
+		seed := rand.Uint64()
+		scenario.Seed = &seed
+	}
+	return
+}
+
+func isFlagSet(name string) (ok bool) {
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			ok = true
+		}
+	})
+	return
+}
+
+// scenarioFromFlags returns the scenario described by the command line flags.
+func scenarioFromFlags(queueType string) (scenario sim.Scenario, err error) {
+	scenario.Engine = *flagEngine
+	scenario.Duration = flagDuration.String()
+	scenario.ResultsBucketingInterval = flagResultsBucketingInterval.String()
+	scenario.TickInterval = flagTickInterval.String()
+	scenario.TasksPerSecond = *flagTasksPerSecond
+	scenario.PriorityWeights = map[string]int{
+		"P0": 100,
+		"P1": 200,
+		"P2": 1000,
+		"P3": 400,
+		"P4": 200,
+	}
+
+	taskMean, taskStdDev := *flagTaskMean, *flagTaskStdDev
+	switch *flagTaskDist {
+	case "normal":
+		scenario.TaskDuration = &sim.DistributionSpec{Type: "normal", Mean: taskMean.String(), StdDev: taskStdDev.String()}
+	case "lognormal":
+		scenario.TaskDuration = &sim.DistributionSpec{Type: "lognormal", Mean: taskMean.String(), StdDev: taskStdDev.String()}
+	case "exponential":
+		scenario.TaskDuration = &sim.DistributionSpec{Type: "exponential", Mean: taskMean.String()}
+	case "pareto":
+		// a shape of 2.5 has a finite mean and variance but a long tail.
+		scenario.TaskDuration = &sim.DistributionSpec{Type: "pareto", Scale: (taskMean * 3 / 5).String(), Shape: 2.5}
+	case "bimodal":
+		// 90% of tasks at half the mean, 10% at 5.5x the mean, which averages out to the mean.
+		scenario.TaskDuration = &sim.DistributionSpec{
+			Type:            "bimodal",
+			Fast:            &sim.DistributionSpec{Type: "normal", Mean: (taskMean / 2).String(), StdDev: (taskStdDev / 2).String()},
+			Slow:            &sim.DistributionSpec{Type: "normal", Mean: (taskMean * 11 / 2).String(), StdDev: (taskStdDev * 11 / 2).String()},
+			SlowProbability: 0.1,
+		}
+	default:
+		err = fmt.Errorf("invalid task duration distribution: %v", *flagTaskDist)
+		return
+	}
+
+	tasksPerSecond := float64(*flagTasksPerSecond)
+	switch *flagArrivals {
+	case "normal", "poisson", "constant":
+		scenario.Arrivals = &sim.ArrivalSpec{Type: *flagArrivals, TasksPerSecond: tasksPerSecond}
+	case "bursty":
+		// 3x the rate for 1 minute, then a third of the rate for 3 minutes, which averages out to the rate.
+		scenario.Arrivals = &sim.ArrivalSpec{
+			Type:              "bursty",
+			OnTasksPerSecond:  3 * tasksPerSecond,
+			OffTasksPerSecond: tasksPerSecond / 3,
+			MeanOnDuration:    time.Minute.String(),
+			MeanOffDuration:   (3 * time.Minute).String(),
+		}
+	case "diurnal":
+		// one full cycle over the simulation, peaking half way through.
+		scenario.Arrivals = &sim.ArrivalSpec{
+			Type:           "diurnal",
+			TasksPerSecond: tasksPerSecond,
+			Amplitude:      0.5,
+			Period:         flagDuration.String(),
+			PeakOffset:     (*flagDuration / 2).String(),
+		}
+	default:
+		err = fmt.Errorf("invalid arrival process: %v", *flagArrivals)
+		return
+	}
+
+	switch *flagTenants {
+	case "default":
+		scenario.Tenants = []sim.TenantSpec{
+			{FairnessKey: "high", Fairness: 70.0, ArrivalWeight: 100},
+			{FairnessKey: "low", Fairness: 10.0, ArrivalWeight: 500},
+			{FairnessKey: "medium", Fairness: 20.0, ArrivalWeight: 1000},
+		}
+	case "noisy":
+		// one noisy, P2 heavy tenant with slow tasks adding half again the arrival rate
+		// on top of many small tenants that share the configured arrival process.
+		scenario.Tenants = append(scenario.Tenants, sim.TenantSpec{
+			FairnessKey:     "noisy",
+			Fairness:        10.0,
+			Arrivals:        &sim.ArrivalSpec{Type: "poisson", TasksPerSecond: tasksPerSecond / 2},
+			PriorityWeights: map[string]int{"P2": 1000, "P3": 100},
+			TaskDuration:    &sim.DistributionSpec{Type: "lognormal", Mean: (3 * taskMean).String(), StdDev: (3 * taskStdDev).String()},
+		})
+		for x := 0; x < 20; x++ {
+			scenario.Tenants = append(scenario.Tenants, sim.TenantSpec{
+				FairnessKey:   fmt.Sprintf("small-%02d", x),
+				Fairness:      10.0,
+				ArrivalWeight: 1,
+			})
+		}
+	case "hierarchical":
+		// two accounts of equal weight, where one account spreads twice the load over many
+		// merchants and job types, such that flat fair queues give it most of the workers.
+		scenario.Tenants = []sim.TenantSpec{
+			{FairnessKey: "acct2/merchant-00/export", Fairness: 10.0, ArrivalWeight: 1000},
+		}
+		for x := 0; x < 10; x++ {
+			for _, jobType := range []string{"export", "sync"} {
+				scenario.Tenants = append(scenario.Tenants, sim.TenantSpec{
+					FairnessKey:   fmt.Sprintf("acct1/merchant-%02d/%s", x, jobType),
+					Fairness:      10.0,
+					ArrivalWeight: 100,
+				})
+			}
+		}
+	default:
+		err = fmt.Errorf("invalid tenants: %v", *flagTenants)
+		return
+	}
+
+	scenario.Queue = sim.QueueSpec{
+		Type:             queueType,
+		CostModel:        *flagCostModel,
+		ConcurrencyLimit: *flagConcurrencyLimit,
+	}
+	if *flagAgingThresholds != "" || *flagAgingInterval > 0 {
+		scenario.Queue.Aging = &sim.AgingSpec{}
+		if *flagAgingThresholds != "" {
+			for _, threshold := range strings.Split(*flagAgingThresholds, ",") {
+				scenario.Queue.Aging.Thresholds = append(scenario.Queue.Aging.Thresholds, strings.TrimSpace(threshold))
+			}
+		}
+		if *flagAgingInterval > 0 {
+			scenario.Queue.Aging.Interval = flagAgingInterval.String()
+		}
+	}
+	if *flagDeadlines != "" {
+		if scenario.Deadlines, err = parseDeadlines(*flagDeadlines); err != nil {
+			return
+		}
+	}
+	if *flagPriorityClassWeights != "" {
+		if scenario.Queue.PriorityClassWeights, err = parsePriorityClassWeights(*flagPriorityClassWeights); err != nil {
+			return
+		}
+	}
+	if *flagPathWeights != "" {
+		if scenario.Queue.PathWeights, err = parsePathWeights(*flagPathWeights); err != nil {
+			return
+		}
+	}
+	if queueType == "feeder" {
+		scenario.Queue.RateLimits = map[string]sim.LimitSpec{
+			"high":   {Actions: 7000, Quantum: "1s"}, // these mirror 70/20/10 for the fk weights
+			"medium": {Actions: 2000, Quantum: "1s"},
+			"low":    {Actions: 1000, Quantum: "1s"},
+		}
+		if *flagCostModel == "worker-time" {
+			// 70/20/10 of the worker task slots, i.e. of the worker seconds available each second.
+			config := sim.SimulationConfig{WorkerCount: scenario.WorkerCount, WorkerTaskSlots: scenario.WorkerTaskSlots}
+			slots := uint32(config.WorkerCountOrDefault() * config.WorkerTaskSlotsOrDefault())
+			scenario.Queue.RateLimits = map[string]sim.LimitSpec{
+				"high":   {Actions: slots * 70 / 100, Quantum: "1s"},
+				"medium": {Actions: slots * 20 / 100, Quantum: "1s"},
+				"low":    {Actions: slots * 10 / 100, Quantum: "1s"},
+			}
+		}
+	}
+	err = scenario.Validate()
+	return
+}
+
+// parsePriorityClassWeights parses weights by priority, e.g. "P0:50,P1:25".
+func parsePriorityClassWeights(value string) (map[string]int, error) {
+	output := make(map[string]int)
+	for _, field := range strings.Split(value, ",") {
+		priority, weight, ok := strings.Cut(strings.TrimSpace(field), ":")
+		parsed, err := strconv.Atoi(weight)
+		if !ok || err != nil {
+			return nil, fmt.Errorf("invalid priority class weight %q: must be of the form P0:50", field)
+		}
+		output[priority] = parsed
+	}
+	return output, nil
+}
+
+// parsePathWeights parses weights by fairness key path, e.g. "acct1:2,acct1/merchant-00:3".
+func parsePathWeights(value string) (map[string]float64, error) {
+	output := make(map[string]float64)
+	for _, field := range strings.Split(value, ",") {
+		path, weight, ok := strings.Cut(strings.TrimSpace(field), ":")
+		parsed, err := strconv.ParseFloat(weight, 64)
+		if !ok || err != nil {
+			return nil, fmt.Errorf("invalid path weight %q: must be of the form acct1:2", field)
+		}
+		output[path] = parsed
+	}
+	return output, nil
+}
+
+// parseDeadlines parses deadlines by priority, e.g. "P0:5s,P1:30s".
+func parseDeadlines(value string) (map[string]string, error) {
+	output := make(map[string]string)
+	for _, field := range strings.Split(value, ",") {
+		priority, deadline, ok := strings.Cut(strings.TrimSpace(field), ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid deadline %q: must be of the form P1:30s", field)
+		}
+		output[priority] = deadline
+	}
+	return output, nil
+}
+
+// maxBucketFairnessKeyColumns is the most fairness keys printed as columns of the
+// bucket table, beyond which the table would be too wide to read.
+const maxBucketFairnessKeyColumns = 8
+
+// printBuckets prints the per bucket results as a table.
+func printBuckets(w io.Writer, res sim.SimulationResults) {
+	fairnessKeys := make(map[string]struct{})
+	for _, bucket := range res.Buckets {
+		for key := range bucket.Results.QueuedP95ByFairnessKey {
+			fairnessKeys[key] = struct{}{}
+		}
+	}
+	keys := sortedKeys(fairnessKeys)
+	if len(keys) > maxBucketFairnessKeyColumns {
+		keys = nil
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprint(tw, "elapsed\ttasks/s\ttql\tp50\tp95\tp99\tjain\tstarved\t")
+	for _, key := range keys {
+		fmt.Fprintf(tw, "p95 %q\t", key)
+	}
+	fmt.Fprintln(tw)
+	for _, bucket := range res.Buckets {
+		fmt.Fprintf(tw, "%v\t%.1f\t%d\t%v\t%v\t%v\t%.3f\t%v\t",
+			bucket.End,
+			bucket.Throughput,
+			bucket.QueueLength,
+			bucket.Results.QueuedP50.Round(time.Millisecond),
+			bucket.Results.QueuedP95.Round(time.Millisecond),
+			bucket.Results.QueuedP99.Round(time.Millisecond),
+			bucket.Results.Fairness.JainIndex,
+			bucket.Results.Fairness.LongestStarvation.Round(time.Millisecond),
+		)
+		for _, key := range keys {
+			fmt.Fprintf(tw, "%v\t", bucket.Results.QueuedP95ByFairnessKey[key].Round(time.Millisecond))
+		}
+		fmt.Fprintln(tw)
+	}
+	_ = tw.Flush()
 }
 
 func sortedKeys[T any](m map[string]T) (output []string) {
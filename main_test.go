@@ -1 +1,22 @@
 package main
+
+import "testing"
+
+func Test_formatDelta(t *testing.T) {
+	for _, c := range []struct {
+		value, baselineValue float64
+		isBaseline           bool
+		expected             string
+	}{
+		{value: 5, baselineValue: 5, isBaseline: true, expected: ""},
+		{value: 5, baselineValue: 5, expected: " (=)"},
+		{value: 6, baselineValue: 5, expected: " (+20.0%)"},
+		{value: 4, baselineValue: 5, expected: " (-20.0%)"},
+		{value: 3, baselineValue: 0, expected: " (+3)"},
+	} {
+		if actual := formatDelta(c.value, c.baselineValue, c.isBaseline, nil); actual != c.expected {
+			t.Errorf("expect delta of %v vs. %v to be %q, was %q", c.value, c.baselineValue, c.expected, actual)
+			t.Fail()
+		}
+	}
+}
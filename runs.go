@@ -0,0 +1,97 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"queue_fairness/sim"
+)
+
+var (
+	flagRuns        = flag.Int("runs", 1, "the number of independently seeded runs, reporting the mean and 95% confidence interval of each metric")
+	flagParallelism = flag.Int("parallelism", 0, "the most runs to simulate at once (defaults to the number of cpus)")
+)
+
+// runMany runs the scenario --runs times and writes the summary of the metrics across the runs.
+func runMany(info io.Writer, scenario sim.Scenario) {
+	if *flagRealTime {
+		fmt.Fprintln(os.Stderr, "--runs cannot be used with --real-time")
+		os.Exit(1)
+	}
+	if *flagReport != "" {
+		fmt.Fprintln(os.Stderr, "--report cannot be used with --runs")
+		os.Exit(1)
+	}
+	writeRuns, err := runsWriter(*flagOutputFormat)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Fprintf(info, "simulating %d runs with seeds %d to %d\n", *flagRuns, *scenario.Seed, *scenario.Seed+uint64(*flagRuns-1))
+	sim.LogOutput = io.Discard
+	start := time.Now()
+	runs, err := sim.RunMany(scenario, *flagRuns, *flagParallelism)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+	fmt.Fprintf(info, "simulations complete! %v elapsed\n", time.Since(start).Round(time.Millisecond).String())
+	fmt.Fprintln(info)
+
+	output := os.Stdout
+	if *flagOutputFile != "" {
+		output, err = os.Create(*flagOutputFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+	}
+	if err = writeRuns(output, runs); err == nil && output != os.Stdout {
+		err = output.Close()
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error writing results: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// runsWriter returns the function that writes the summary of runs in a given output format.
+func runsWriter(format string) (func(io.Writer, sim.Runs) error, error) {
+	switch format {
+	case "text":
+		return printRuns, nil
+	case "json":
+		return sim.WriteRunsJSON, nil
+	case "csv":
+		return sim.WriteRunsCSV, nil
+	default:
+		return nil, fmt.Errorf("invalid output format: %v", format)
+	}
+}
+
+// printRuns writes the summary of the run level metrics as a human readable table;
+// the bucket and phase metrics are only included in the json and csv output.
+func printRuns(w io.Writer, runs sim.Runs) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "dimension\tkey\tmetric\truns\tmean\t95% ci\t")
+	for _, m := range runs.Metrics() {
+		if m.Scope != "run" {
+			continue
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%s\t± %s\t\n",
+			m.Dimension,
+			m.DimensionKey,
+			m.Name,
+			m.Runs,
+			strconv.FormatFloat(m.Mean, 'g', 6, 64),
+			strconv.FormatFloat(m.CI95, 'g', 3, 64),
+		)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,71 @@
+package sim
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// AgingPolicy raises the effective priority of queued tasks as they wait, such that lower
+// priority tasks are served eventually even while higher priority tasks keep arriving.
+//
+// A task is raised one priority for each of the thresholds its queued time has exceeded,
+// and one priority for every interval it has been queued, up to P0. The "priority" task queue
+// serves tasks of the same effective priority oldest first, so once a task has been raised to
+// P0 it is served ahead of every task that arrived after it, which bounds how long it waits.
+// The "fairness" task queue serves the highest effective priority too, but chooses between the
+// fairness keys at that priority by weight, so a raised task still shares it with the other keys,
+// and with class weights even P0 only gets its share of the dispatches.
+//
+// The zero value never raises a task's priority.
+type AgingPolicy struct {
+	// Thresholds are the queued times, ascending, at which a task is raised one priority each.
+	Thresholds []time.Duration
+	// Interval, if set, raises a task one priority for every interval it has been queued.
+	Interval time.Duration
+}
+
+// IsZero returns if the policy never raises a task's priority.
+func (ap AgingPolicy) IsZero() bool {
+	return len(ap.Thresholds) == 0 && ap.Interval <= 0
+}
+
+// Boost returns how many priorities a task that has been queued for a given time is raised by.
+func (ap AgingPolicy) Boost(queued time.Duration) (boost int) {
+	for _, threshold := range ap.Thresholds {
+		if queued >= threshold {
+			boost++
+		}
+	}
+	if ap.Interval > 0 {
+		boost += int(queued / ap.Interval)
+	}
+	return
+}
+
+// EffectivePriority returns the priority of a task as of a given time, raised by how long it has been queued.
+func (ap AgingPolicy) EffectivePriority(t *Task, now time.Time) Priority {
+	if ap.IsZero() {
+		return t.Priority
+	}
+	return max(t.Priority-Priority(ap.Boost(now.Sub(t.CreatedUTC))), P0)
+}
+
+// String returns a description of the policy, e.g. "after 5m0s, 15m0s and every 30m0s".
+func (ap AgingPolicy) String() string {
+	if ap.IsZero() {
+		return "none"
+	}
+	var parts []string
+	if len(ap.Thresholds) > 0 {
+		thresholds := make([]string, len(ap.Thresholds))
+		for index, threshold := range ap.Thresholds {
+			thresholds[index] = threshold.String()
+		}
+		parts = append(parts, "after "+strings.Join(thresholds, ", "))
+	}
+	if ap.Interval > 0 {
+		parts = append(parts, fmt.Sprintf("every %v", ap.Interval))
+	}
+	return strings.Join(parts, " and ")
+}
@@ -0,0 +1,117 @@
+package sim
+
+import (
+	"math/rand/v2"
+	"testing"
+	"time"
+)
+
+func Test_AgingPolicy_EffectivePriority(t *testing.T) {
+	created := SimulatedStartTime
+	task := &Task{Priority: P4, CreatedUTC: created}
+
+	thresholds := AgingPolicy{Thresholds: []time.Duration{5 * time.Minute, 10 * time.Minute}}
+	for queued, expected := range map[time.Duration]Priority{0: P4, 5 * time.Minute: P3, 12 * time.Minute: P2, time.Hour: P2} {
+		if p := thresholds.EffectivePriority(task, created.Add(queued)); p != expected {
+			t.Errorf("expect a P4 task queued for %v to be %v, was %v", queued, Priority(expected).String(), p.String())
+			t.Fail()
+		}
+	}
+
+	linear := AgingPolicy{Interval: time.Minute}
+	if p := linear.EffectivePriority(task, created.Add(150*time.Second)); p != P2 {
+		t.Errorf("expect a P4 task queued for 2m30s to be P2, was %v", p.String())
+		t.Fail()
+	}
+	if p := linear.EffectivePriority(task, created.Add(time.Hour)); p != P0 {
+		t.Errorf("expect a P4 task to be raised no higher than P0, was %v", p.String())
+		t.Fail()
+	}
+	if p := (AgingPolicy{}).EffectivePriority(task, created.Add(time.Hour)); p != P4 {
+		t.Errorf("expect no aging policy to keep the priority, was %v", p.String())
+		t.Fail()
+	}
+}
+
+func Test_PrioritySortedTaskQueue_aging(t *testing.T) {
+	clock := NewSimulatedClock(SimulatedStartTime)
+	q := NewPrioritySortedTaskQueue(clock, AgingPolicy{Thresholds: []time.Duration{time.Minute, 2 * time.Minute}})
+
+	q.Push(Task{ID: NewUUID(), Priority: P3, CreatedUTC: clock.Now()})
+	clock.Wait(30 * time.Second)
+	q.Push(Task{ID: NewUUID(), Priority: P1, CreatedUTC: clock.Now()})
+	q.Push(Task{ID: NewUUID(), Priority: P2, CreatedUTC: clock.Now()})
+
+	task, _ := q.Pull()
+	if task.Priority != P1 || task.Boost != 0 {
+		t.Errorf("expect the P1 task first, was %v boosted %d", task.Priority.String(), task.Boost)
+		t.Fail()
+	}
+
+	// the P3 task is raised to P1 after two minutes, ahead of the (younger) P2 task raised to P1 too.
+	clock.Wait(90 * time.Second)
+	task, _ = q.Pull()
+	if task.Priority != P3 || task.Boost != 2 {
+		t.Errorf("expect the P3 task boosted by 2 next, was %v boosted %d", task.Priority.String(), task.Boost)
+		t.Fail()
+	}
+	task, _ = q.Pull()
+	if task.Priority != P2 || task.Boost != 1 {
+		t.Errorf("expect the P2 task boosted by 1 last, was %v boosted %d", task.Priority.String(), task.Boost)
+		t.Fail()
+	}
+	if _, ok := q.Pull(); ok || q.Len() != 0 {
+		t.Errorf("expect the queue to be empty")
+		t.Fail()
+	}
+}
+
+func Test_PriorityFairnessTaskQueue_aging(t *testing.T) {
+	clock := NewSimulatedClock(SimulatedStartTime)
+	q := NewPriorityFairnessTaskQueue(rand.New(rand.NewPCG(1, 2)), clock, CostModelTaskCount, AgingPolicy{Interval: time.Minute}, nil)
+
+	q.Push(Task{ID: NewUUID(), Priority: P4, FairnessKey: "batch", Fairness: 1, CreatedUTC: clock.Now()})
+	clock.Wait(3 * time.Minute)
+	for x := 0; x < 10; x++ {
+		q.Push(Task{ID: NewUUID(), Priority: P2, FairnessKey: "interactive", Fairness: 100, CreatedUTC: clock.Now()})
+	}
+
+	// the P4 task has been raised to P1 and so is served ahead of the P2 tasks despite its weight.
+	task, _ := q.Pull()
+	if task.FairnessKey != "batch" || task.Boost != 3 {
+		t.Errorf("expect the aged batch task first, was %q boosted %d", task.FairnessKey, task.Boost)
+		t.Fail()
+	}
+	task, _ = q.Pull()
+	if task.FairnessKey != "interactive" || task.Boost != 0 {
+		t.Errorf("expect an interactive task that was not boosted next, was %q boosted %d", task.FairnessKey, task.Boost)
+		t.Fail()
+	}
+}
+
+func Test_PriorityFairnessTaskQueue_agingClassWeights(t *testing.T) {
+	clock := NewSimulatedClock(SimulatedStartTime)
+	q := NewPriorityFairnessTaskQueue(rand.New(rand.NewPCG(1, 2)), clock, CostModelTaskCount, AgingPolicy{Interval: time.Minute}, map[Priority]int{P0: 1, P2: 9})
+
+	q.Push(Task{ID: NewUUID(), Priority: P4, FairnessKey: "batch", Fairness: 1, CreatedUTC: clock.Now()})
+	clock.Wait(5 * time.Minute)
+	for x := 0; x < 1000; x++ {
+		q.Push(Task{ID: NewUUID(), Priority: P2, FairnessKey: "interactive", Fairness: 1, CreatedUTC: clock.Now()})
+	}
+
+	// the P4 task has been raised to P0, and so is served with P0's share of the dispatches
+	// (rather than ahead of every other task), i.e. within ~10 pulls.
+	for x := 0; x < 100; x++ {
+		task, _ := q.Pull()
+		if task.FairnessKey != "batch" {
+			continue
+		}
+		if task.Boost != 4 {
+			t.Errorf("expect the batch task to be boosted to P0, was boosted %d", task.Boost)
+			t.Fail()
+		}
+		return
+	}
+	t.Errorf("expect the aged batch task to be served within 100 pulls")
+	t.Fail()
+}
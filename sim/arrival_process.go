@@ -0,0 +1,132 @@
+package sim
+
+import (
+	"math"
+	"math/rand/v2"
+	"time"
+)
+
+// ArrivalProcess determines how many tasks arrive in a window of simulated time.
+//
+// Implementations may be stateful (see [BurstyArrivals]), and as such
+// should not be shared between simulations.
+type ArrivalProcess interface {
+	// Arrivals returns the number of tasks arriving in the window
+	// that starts the given offset after the start of the simulation.
+	//
+	// Windows are requested in order and do not overlap.
+	Arrivals(r *rand.Rand, offset, window time.Duration) int
+}
+
+// NormalArrivals draws the number of arrivals in a window from a normal distribution
+// whose mean and standard deviation are both the expected number of arrivals.
+//
+// This is the original arrival behavior of the simulation; note that it
+// produces no arrivals roughly a sixth of the time.
+type NormalArrivals struct {
+	TasksPerSecond float64
+}
+
+// Arrivals implements [ArrivalProcess].
+func (na NormalArrivals) Arrivals(r *rand.Rand, _, window time.Duration) int {
+	tasksMean := math.Floor(na.TasksPerSecond * window.Seconds())
+	return int(RandomNormal(r, tasksMean, tasksMean))
+}
+
+// PoissonArrivals is a poisson process, i.e. tasks arrive
+// independently of each other at a constant average rate.
+type PoissonArrivals struct {
+	TasksPerSecond float64
+}
+
+// Arrivals implements [ArrivalProcess].
+func (pa PoissonArrivals) Arrivals(r *rand.Rand, _, window time.Duration) int {
+	return RandomPoisson(r, pa.TasksPerSecond*window.Seconds())
+}
+
+// ConstantArrivals produces arrivals at exactly a constant rate with no variance.
+type ConstantArrivals struct {
+	TasksPerSecond float64
+}
+
+// Arrivals implements [ArrivalProcess].
+func (ca ConstantArrivals) Arrivals(_ *rand.Rand, offset, window time.Duration) int {
+	before := math.Floor(ca.TasksPerSecond * offset.Seconds())
+	after := math.Floor(ca.TasksPerSecond * (offset + window).Seconds())
+	return int(after - before)
+}
+
+// BurstyArrivals is a markov-modulated poisson process that alternates between
+// an "on" (burst) and an "off" state, with exponentially distributed
+// time spent in each state.
+//
+// The long run average rate is the per-state rates weighted by the mean state durations.
+type BurstyArrivals struct {
+	OnTasksPerSecond  float64
+	OffTasksPerSecond float64
+	MeanOnDuration    time.Duration
+	MeanOffDuration   time.Duration
+
+	started   bool
+	on        bool
+	remaining time.Duration
+}
+
+// Arrivals implements [ArrivalProcess].
+func (ba *BurstyArrivals) Arrivals(r *rand.Rand, _, window time.Duration) int {
+	if !ba.started {
+		ba.started = true
+		ba.on = r.Float64() < float64(ba.MeanOnDuration)/float64(ba.MeanOnDuration+ba.MeanOffDuration)
+		ba.remaining = ba.randomStateDuration(r)
+	}
+	var tasksMean float64
+	for window > 0 {
+		segment := min(window, ba.remaining)
+		if ba.on {
+			tasksMean += ba.OnTasksPerSecond * segment.Seconds()
+		} else {
+			tasksMean += ba.OffTasksPerSecond * segment.Seconds()
+		}
+		window -= segment
+		ba.remaining -= segment
+		if ba.remaining <= 0 {
+			ba.on = !ba.on
+			ba.remaining = ba.randomStateDuration(r)
+		}
+	}
+	return RandomPoisson(r, tasksMean)
+}
+
+func (ba *BurstyArrivals) randomStateDuration(r *rand.Rand) time.Duration {
+	mean := ba.MeanOffDuration
+	if ba.on {
+		mean = ba.MeanOnDuration
+	}
+	return max(time.Duration(r.ExpFloat64()*float64(mean)), time.Nanosecond)
+}
+
+// DiurnalArrivals is a poisson process whose rate follows a sinusoid, e.g. to
+// model daily traffic peaks and troughs.
+//
+// The rate peaks at (1+Amplitude) times the mean rate at PeakOffset (and every Period thereafter),
+// and bottoms out at (1-Amplitude) times the mean rate half a period later.
+type DiurnalArrivals struct {
+	MeanTasksPerSecond float64
+	Amplitude          float64
+	Period             time.Duration
+	PeakOffset         time.Duration
+}
+
+// Arrivals implements [ArrivalProcess].
+func (da DiurnalArrivals) Arrivals(r *rand.Rand, offset, window time.Duration) int {
+	if da.Period <= 0 {
+		return RandomPoisson(r, da.MeanTasksPerSecond*window.Seconds())
+	}
+	// integrate the rate over the window exactly.
+	period := da.Period.Seconds()
+	phase := func(at time.Duration) float64 {
+		return 2 * math.Pi * (at - da.PeakOffset).Seconds() / period
+	}
+	integral := window.Seconds() + da.Amplitude*period/(2*math.Pi)*(math.Sin(phase(offset+window))-math.Sin(phase(offset)))
+	return RandomPoisson(r, da.MeanTasksPerSecond*integral)
+}
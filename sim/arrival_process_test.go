@@ -0,0 +1,53 @@
+package sim
+
+import (
+	"math"
+	"math/rand/v2"
+	"testing"
+	"time"
+)
+
+func Test_ArrivalProcess_rates(t *testing.T) {
+	testCases := []struct {
+		Name    string
+		Process ArrivalProcess
+	}{
+		{Name: "poisson", Process: PoissonArrivals{TasksPerSecond: 1000}},
+		{Name: "constant", Process: ConstantArrivals{TasksPerSecond: 1000}},
+		{Name: "bursty", Process: &BurstyArrivals{OnTasksPerSecond: 3000, OffTasksPerSecond: 1000.0 / 3.0, MeanOnDuration: time.Second, MeanOffDuration: 3 * time.Second}},
+		{Name: "diurnal", Process: DiurnalArrivals{MeanTasksPerSecond: 1000, Amplitude: 0.5, Period: time.Hour}},
+	}
+	for _, tc := range testCases {
+		r := rand.New(rand.NewPCG(123, 123))
+		window := 500 * time.Millisecond
+		var total int
+		for offset := time.Duration(0); offset < time.Hour; offset += window {
+			count := tc.Process.Arrivals(r, offset, window)
+			if count < 0 {
+				t.Errorf("%s: expect arrivals to never be negative, was %d", tc.Name, count)
+				t.FailNow()
+			}
+			total += count
+		}
+		rate := float64(total) / time.Hour.Seconds()
+		if math.Abs(rate-1000) > 20 {
+			t.Errorf("%s: expect average rate to be within 2%% of 1000/s, was %0.2f/s", tc.Name, rate)
+			t.Fail()
+		}
+	}
+}
+
+func Test_ConstantArrivals(t *testing.T) {
+	ca := ConstantArrivals{TasksPerSecond: 3}
+	var counts []int
+	for offset := time.Duration(0); offset < time.Second; offset += 250 * time.Millisecond {
+		counts = append(counts, ca.Arrivals(nil, offset, 250*time.Millisecond))
+	}
+	expected := []int{0, 1, 1, 1}
+	for x := range expected {
+		if counts[x] != expected[x] {
+			t.Errorf("expect counts to be %v, was %v", expected, counts)
+			t.FailNow()
+		}
+	}
+}
@@ -13,6 +13,10 @@ type WallClock struct{}
 func (wc WallClock) Now() time.Time       { return time.Now() }
 func (wc WallClock) Wait(d time.Duration) { time.Sleep(d) }
 
+// SimulatedStartTime is the time simulated clocks start from by default, such
+// that results don't depend on when a simulation was run.
+var SimulatedStartTime = time.Date(2024, 01, 01, 00, 00, 00, 00, time.UTC)
+
 func NewSimulatedClock(startAt time.Time) Clock {
 	return &simulatedClock{ts: startAt}
 }
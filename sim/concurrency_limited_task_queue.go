@@ -0,0 +1,95 @@
+package sim
+
+// ConcurrencyLimiter is implemented by task queues whose per fairness key
+// concurrency limits can be changed while the simulation runs.
+type ConcurrencyLimiter interface {
+	ConcurrencyLimits() (defaultLimit int, limitsByFairnessKey map[string]int)
+	SetConcurrencyLimits(defaultLimit int, limitsByFairnessKey map[string]int)
+}
+
+// NewConcurrencyLimitedTaskQueue wraps a task queue such that at most a given number of tasks
+// per fairness key are dispatched to workers at once, i.e. held in worker task slots.
+//
+// Keys without an entry in the limits map use the default limit, and limits of zero
+// (or less) are treated as unlimited.
+//
+// The inner queue pulls the next task of the keys under their limits, so the tasks of keys
+// at their limits stay queued in it, in order, and are not charged until they are dispatched.
+func NewConcurrencyLimitedTaskQueue(inner FilteredTaskQueue, defaultLimit int, limitsByFairnessKey map[string]int) TaskQueue {
+	q := &concurrencyLimitedTaskQueue{
+		inner:    inner,
+		inFlight: make(inFlight),
+	}
+	q.SetConcurrencyLimits(defaultLimit, limitsByFairnessKey)
+	return q
+}
+
+type concurrencyLimitedTaskQueue struct {
+	inner        FilteredTaskQueue
+	defaultLimit int
+	limits       map[string]int
+	inFlight
+}
+
+func (q *concurrencyLimitedTaskQueue) Len() int {
+	return q.inner.Len()
+}
+
+func (q *concurrencyLimitedTaskQueue) Push(t Task) {
+	q.inner.Push(t)
+}
+
+func (q *concurrencyLimitedTaskQueue) Pull() (task *Task, ok bool) {
+	task, ok = q.inner.PullFiltered(q.allow)
+	if ok {
+		q.inFlight.dispatched(task)
+	}
+	return
+}
+
+// OnComplete implements [CompletionObserver].
+func (q *concurrencyLimitedTaskQueue) OnComplete(t *Task) {
+	q.inFlight.completed(t)
+	if observer, ok := q.inner.(CompletionObserver); ok {
+		observer.OnComplete(t)
+	}
+}
+
+// Unwrap implements [Wrapper].
+func (q *concurrencyLimitedTaskQueue) Unwrap() TaskQueue {
+	return q.inner
+}
+
+// ConcurrencyLimits implements [ConcurrencyLimiter].
+func (q *concurrencyLimitedTaskQueue) ConcurrencyLimits() (defaultLimit int, limitsByFairnessKey map[string]int) {
+	limitsByFairnessKey = make(map[string]int, len(q.limits))
+	for key, limit := range q.limits {
+		limitsByFairnessKey[key] = limit
+	}
+	return q.defaultLimit, limitsByFairnessKey
+}
+
+// SetConcurrencyLimits implements [ConcurrencyLimiter].
+//
+// Lowering a limit does not preempt tasks already held by workers, but holds
+// back further tasks for the fairness key until it is under the new limit.
+func (q *concurrencyLimitedTaskQueue) SetConcurrencyLimits(defaultLimit int, limitsByFairnessKey map[string]int) {
+	q.defaultLimit = defaultLimit
+	q.limits = make(map[string]int, len(limitsByFairnessKey))
+	for key, limit := range limitsByFairnessKey {
+		q.limits[key] = limit
+	}
+}
+
+func (q *concurrencyLimitedTaskQueue) allow(fairnessKey string) bool {
+	limit, ok := q.limits[fairnessKey]
+	if !ok {
+		limit = q.defaultLimit
+	}
+	return limit <= 0 || q.inFlight.InFlight(fairnessKey) < limit
+}
+
+// Drain implements [Drainer].
+func (q *concurrencyLimitedTaskQueue) Drain() []*Task {
+	return DrainTaskQueue(q.inner)
+}
@@ -0,0 +1,133 @@
+package sim
+
+import (
+	"math/rand/v2"
+	"testing"
+	"time"
+)
+
+func Test_ConcurrencyLimitedTaskQueue(t *testing.T) {
+	rq := NewConcurrencyLimitedTaskQueue(NewSimpleTaskQueue().(FilteredTaskQueue), 2, map[string]int{"low": 1})
+
+	for x := 0; x < 3; x++ {
+		rq.Push(Task{ID: NewUUID(), FairnessKey: "low"})
+	}
+	for x := 0; x < 3; x++ {
+		rq.Push(Task{ID: NewUUID(), FairnessKey: "high"})
+	}
+
+	if rq.Len() != 6 {
+		t.Errorf("expect tq length to be 6, was %d", rq.Len())
+		t.Fail()
+	}
+
+	var pulled []*Task
+	for {
+		task, ok := rq.Pull()
+		if !ok {
+			break
+		}
+		pulled = append(pulled, task)
+	}
+	if len(pulled) != 3 {
+		t.Errorf("expect 3 tasks to be pulled before hitting the limits, was %d", len(pulled))
+		t.FailNow()
+	}
+	if rq.Len() != 3 {
+		t.Errorf("expect tq length to be 3, was %d", rq.Len())
+		t.Fail()
+	}
+
+	rq.(CompletionObserver).OnComplete(pulled[0])
+	task, ok := rq.Pull()
+	if !ok {
+		t.Errorf("expect pull to be ok after a completion")
+		t.FailNow()
+	}
+	if task.FairnessKey != pulled[0].FairnessKey {
+		t.Errorf("expect pull to be from %q, was %q", pulled[0].FairnessKey, task.FairnessKey)
+		t.Fail()
+	}
+	if _, ok := rq.Pull(); ok {
+		t.Errorf("expect pull to not be ok while at the limits")
+		t.Fail()
+	}
+
+	drained := DrainTaskQueue(rq)
+	if len(drained) != 2 {
+		t.Errorf("expect 2 tasks to be drained, was %d", len(drained))
+		t.Fail()
+	}
+	if rq.Len() != 0 {
+		t.Errorf("expect tq length to be 0, was %d", rq.Len())
+		t.Fail()
+	}
+}
+
+func Test_ConcurrencyLimitedTaskQueue_priority(t *testing.T) {
+	inner := NewPrioritySortedTaskQueue(NewSimulatedClock(SimulatedStartTime), AgingPolicy{})
+	rq := NewConcurrencyLimitedTaskQueue(inner.(FilteredTaskQueue), 1, nil)
+
+	rq.Push(Task{ID: NewUUID(), FairnessKey: "low", Priority: P2})
+	rq.Push(Task{ID: NewUUID(), FairnessKey: "low", Priority: P2})
+	first, _ := rq.Pull()
+	if _, ok := rq.Pull(); ok {
+		t.Errorf("expect pull to not be ok while low is at its limit")
+		t.Fail()
+	}
+
+	// the task of low held back stays queued in the inner queue, so it doesn't jump ahead of higher priorities.
+	rq.Push(Task{ID: NewUUID(), FairnessKey: "high", Priority: P0})
+	rq.(CompletionObserver).OnComplete(first)
+	expected := []string{"high", "low"}
+	for x, expectedKey := range expected {
+		task, ok := rq.Pull()
+		if !ok {
+			t.Errorf("expect pull to be ok")
+			t.FailNow()
+		}
+		if task.FairnessKey != expectedKey {
+			t.Errorf("expect pull %d to be from %q, was %q", x, expectedKey, task.FairnessKey)
+			t.Fail()
+		}
+	}
+}
+
+func Test_FilteredTaskQueue(t *testing.T) {
+	for _, queueType := range TaskQueueTypes {
+		tq, err := NewTaskQueue(TaskQueueOptions{Type: queueType, CostModel: CostModelTaskCount}, rand.New(rand.NewPCG(1, 2)), NewSimulatedClock(time.Now()))
+		if err != nil {
+			t.Errorf("%s: expect no error, was %v", queueType, err)
+			t.FailNow()
+		}
+		rq := tq.(FilteredTaskQueue)
+		for x := 0; x < 3; x++ {
+			rq.Push(Task{ID: NewUUID(), FairnessKey: "acct1/blocked", Fairness: 1, Priority: P0})
+			rq.Push(Task{ID: NewUUID(), FairnessKey: "acct1/allowed", Fairness: 1, Priority: P2})
+		}
+		allow := func(fairnessKey string) bool { return fairnessKey != "acct1/blocked" }
+		for x := 0; x < 3; x++ {
+			task, ok := rq.PullFiltered(allow)
+			if !ok {
+				t.Errorf("%s: expect pull %d to be ok", queueType, x)
+				t.FailNow()
+			}
+			if task.FairnessKey != "acct1/allowed" {
+				t.Errorf("%s: expect pull %d to be from %q, was %q", queueType, x, "acct1/allowed", task.FairnessKey)
+				t.Fail()
+			}
+		}
+		if _, ok := rq.PullFiltered(allow); ok {
+			t.Errorf("%s: expect pull to not be ok with only blocked tasks queued", queueType)
+			t.Fail()
+		}
+		if rq.Len() != 3 {
+			t.Errorf("%s: expect tq length to be 3, was %d", queueType, rq.Len())
+			t.Fail()
+		}
+		if _, ok := rq.Pull(); !ok {
+			t.Errorf("%s: expect an unfiltered pull to be ok", queueType)
+			t.Fail()
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package sim
+
+import "time"
+
+// concurrencyStats tracks the number of tasks held in worker task slots
+// per fairness key over time, i.e. the concurrency of each key.
+type concurrencyStats struct {
+	start    time.Time
+	last     time.Time
+	current  map[string]int
+	max      map[string]int
+	integral map[string]time.Duration // task-duration, i.e. concurrency times elapsed time
+}
+
+func newConcurrencyStats(start time.Time) *concurrencyStats {
+	return &concurrencyStats{
+		start:    start,
+		last:     start,
+		current:  make(map[string]int),
+		max:      make(map[string]int),
+		integral: make(map[string]time.Duration),
+	}
+}
+
+func (cs *concurrencyStats) dispatched(now time.Time, fairnessKey string) {
+	cs.advance(now)
+	cs.current[fairnessKey]++
+	if cs.current[fairnessKey] > cs.max[fairnessKey] {
+		cs.max[fairnessKey] = cs.current[fairnessKey]
+	}
+}
+
+func (cs *concurrencyStats) completed(now time.Time, fairnessKey string) {
+	cs.advance(now)
+	cs.current[fairnessKey]--
+}
+
+// advance accumulates the concurrency of each key since the last change.
+func (cs *concurrencyStats) advance(now time.Time) {
+	elapsed := now.Sub(cs.last)
+	if elapsed <= 0 {
+		return
+	}
+	for key, count := range cs.current {
+		cs.integral[key] += time.Duration(count) * elapsed
+	}
+	cs.last = now
+}
+
+// avg returns the time weighted average concurrency of each key up to a given time.
+func (cs *concurrencyStats) avg(now time.Time) map[string]float64 {
+	cs.advance(now)
+	output := make(map[string]float64, len(cs.integral))
+	elapsed := now.Sub(cs.start)
+	if elapsed <= 0 {
+		return output
+	}
+	for key, integral := range cs.integral {
+		output[key] = float64(integral) / float64(elapsed)
+	}
+	return output
+}
@@ -0,0 +1,71 @@
+package sim
+
+import (
+	"fmt"
+	"time"
+)
+
+// CostModel determines what the fair task queues charge a fairness key
+// for each task they dispatch.
+type CostModel int
+
+const (
+	// CostModelTaskCount charges one unit per task, i.e. shares are
+	// measured in the number of tasks pulled.
+	CostModelTaskCount CostModel = iota
+	// CostModelWorkerTime charges the worker-seconds a task occupies, estimated
+	// from the work duration at pull time and corrected with the
+	// actual duration when the task completes.
+	CostModelWorkerTime
+)
+
+// ParseCostModel parses a cost model from its string form.
+func ParseCostModel(value string) (CostModel, error) {
+	switch value {
+	case "count", "":
+		return CostModelTaskCount, nil
+	case "worker-time":
+		return CostModelWorkerTime, nil
+	default:
+		return CostModelTaskCount, fmt.Errorf("invalid cost model: %q", value)
+	}
+}
+
+func (cm CostModel) String() string {
+	switch cm {
+	case CostModelTaskCount:
+		return "count"
+	case CostModelWorkerTime:
+		return "worker-time"
+	default:
+		return ""
+	}
+}
+
+// Estimate returns the cost of a task at the time it is pulled.
+func (cm CostModel) Estimate(t *Task) float64 {
+	if cm == CostModelWorkerTime {
+		return durationSeconds(t.WorkDuration)
+	}
+	return 1.0
+}
+
+// Actual returns the cost of a task once it has completed.
+func (cm CostModel) Actual(t *Task) float64 {
+	if cm == CostModelWorkerTime {
+		return durationSeconds(t.CompletedUTC.Sub(t.DispatchedUTC))
+	}
+	return 1.0
+}
+
+// Correction returns the difference between the actual and estimated cost of a completed task.
+func (cm CostModel) Correction(t *Task) float64 {
+	return cm.Actual(t) - cm.Estimate(t)
+}
+
+func durationSeconds(d time.Duration) float64 {
+	if d < 0 {
+		return 0
+	}
+	return float64(d) / float64(time.Second)
+}
@@ -0,0 +1,41 @@
+package sim
+
+import "time"
+
+// DeadlineResults count the tasks with a deadline and how many of them missed it, i.e.
+// were dispatched after it, or were still queued past it at the end of the simulation.
+//
+// Tasks still queued at the end of the simulation whose deadline had not passed yet are not counted.
+type DeadlineResults struct {
+	Tasks  int
+	Missed int
+}
+
+// MissRate returns the fraction of the tasks with a deadline that missed it.
+func (dr DeadlineResults) MissRate() float64 {
+	if dr.Tasks == 0 {
+		return 0
+	}
+	return float64(dr.Missed) / float64(dr.Tasks)
+}
+
+func (dr *DeadlineResults) add(missed bool) {
+	dr.Tasks++
+	if missed {
+		dr.Missed++
+	}
+}
+
+func (dr *DeadlineResults) merge(other DeadlineResults) {
+	dr.Tasks += other.Tasks
+	dr.Missed += other.Missed
+}
+
+// deadlineMissed returns if a task has a deadline and whether it was missed as of the time it was
+// dispatched, or as of the end of the simulation if it is still queued.
+func deadlineMissed(t *Task, dispatchedOrFinal time.Time) (hasDeadline, missed bool) {
+	if t.Deadline.IsZero() {
+		return false, false
+	}
+	return true, dispatchedOrFinal.After(t.Deadline)
+}
@@ -0,0 +1,128 @@
+package sim
+
+// NewDeficitRoundRobinTaskQueue returns a new deficit round robin task queue.
+//
+// The "drr" task queue keeps a fifo lane per fairness key and visits the lanes
+// with queued work in round-robin order. Each visit credits the lane with a quantum
+// equal to the task's fairness weight, and the lane is drained while it has
+// enough credit to cover the cost of the task at its head; unspent credit carries over to the next round.
+//
+// Unlike the "fairness" task queue, the shares are enforced deterministically
+// over every round rather than on average, and each pull is amortized O(1).
+//
+// With the [CostModelTaskCount] cost model each task costs one unit of credit, and with
+// [CostModelWorkerTime] each task costs its worker-seconds, so a quantum is a
+// number of tasks or worker-seconds respectively.
+//
+// Priority is ignored; tasks are served in arrival order within a fairness key.
+func NewDeficitRoundRobinTaskQueue(costModel CostModel) TaskQueue {
+	return &deficitRoundRobinTaskQueue{
+		costModel: costModel,
+		storage:   make(map[string]*Queue[*Task]),
+		quantums:  make(map[string]float64),
+		deficits:  make(map[string]float64),
+		active:    &Queue[string]{},
+		inFlight:  make(inFlight),
+	}
+}
+
+type deficitRoundRobinTaskQueue struct {
+	costModel CostModel
+	len       int
+	storage   map[string]*Queue[*Task]
+	quantums  map[string]float64
+	deficits  map[string]float64
+	active    *Queue[string]
+	// credited is true if the lane at the head of the active ring
+	// has already received its quantum for the current round.
+	credited bool
+	inFlight
+}
+
+func (q *deficitRoundRobinTaskQueue) Len() int {
+	return q.len
+}
+
+func (q *deficitRoundRobinTaskQueue) Push(t Task) {
+	lane, ok := q.storage[t.FairnessKey]
+	if !ok {
+		lane = &Queue[*Task]{}
+		q.storage[t.FairnessKey] = lane
+	}
+	if lane.Len() == 0 {
+		q.active.Push(t.FairnessKey)
+	}
+	lane.Push(&t)
+	q.quantums[t.FairnessKey] = fairnessWeightOf(&t)
+	q.len++
+}
+
+func (q *deficitRoundRobinTaskQueue) Pull() (task *Task, ok bool) {
+	return q.PullFiltered(allowAllFairnessKeys)
+}
+
+// PullFiltered pulls the next task of the fairness keys allow returns true for, as if the
+// tasks of the other fairness keys were not queued.
+//
+// Lanes that are not allowed are passed over without being credited a quantum.
+func (q *deficitRoundRobinTaskQueue) PullFiltered(allow func(fairnessKey string) bool) (task *Task, ok bool) {
+	// skipped is the number of lanes in a row passed over, so we stop once none are allowed.
+	for skipped := 0; skipped < q.active.Len(); {
+		key, _ := q.active.Peek()
+		if !allow(key) {
+			_, _ = q.active.Pop()
+			q.active.Push(key)
+			q.credited = false
+			skipped++
+			continue
+		}
+		skipped = 0
+		lane := q.storage[key]
+		head, _ := lane.Peek()
+		if cost := q.costModel.Estimate(head); q.deficits[key] >= cost {
+			task, ok = lane.Pop()
+			q.deficits[key] -= cost
+			q.len--
+			q.inFlight.dispatched(task)
+			if lane.Len() == 0 {
+				// idle lanes do not bank credit.
+				_, _ = q.active.Pop()
+				q.deficits[key] = 0
+				q.credited = false
+			}
+			return
+		}
+		if !q.credited {
+			q.deficits[key] += q.quantums[key]
+			q.credited = true
+			continue
+		}
+		_, _ = q.active.Pop()
+		q.active.Push(key)
+		q.credited = false
+	}
+	return
+}
+
+// OnComplete implements [CompletionObserver].
+//
+// The difference between the estimated and actual cost of the task is charged
+// against the key's deficit if the key is still backlogged.
+func (q *deficitRoundRobinTaskQueue) OnComplete(t *Task) {
+	q.inFlight.completed(t)
+	if q.costModel == CostModelTaskCount {
+		return
+	}
+	if lane, ok := q.storage[t.FairnessKey]; ok && lane.Len() > 0 {
+		q.deficits[t.FairnessKey] -= q.costModel.Correction(t)
+	}
+}
+
+// fairnessWeightOf returns the task's fairness weight, treating
+// unset (or invalid) weights as a weight of one.
+func fairnessWeightOf(t *Task) float64 {
+	if t.Fairness > 0 {
+		return t.Fairness
+	}
+	return 1.0
+}
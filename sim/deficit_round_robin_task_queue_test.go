@@ -0,0 +1,78 @@
+package sim
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_DeficitRoundRobinTaskQueue(t *testing.T) {
+	rq := NewDeficitRoundRobinTaskQueue(CostModelTaskCount)
+
+	for x := 0; x < 20; x++ {
+		rq.Push(Task{ID: NewUUID(), FairnessKey: "high", Fairness: 7})
+		rq.Push(Task{ID: NewUUID(), FairnessKey: "medium", Fairness: 2})
+		rq.Push(Task{ID: NewUUID(), FairnessKey: "low", Fairness: 1})
+	}
+
+	if rq.Len() != 60 {
+		t.Errorf("expect tq length to be 60, was %d", rq.Len())
+		t.Fail()
+	}
+
+	counts := make(map[string]int)
+	for x := 0; x < 20; x++ {
+		task, ok := rq.Pull()
+		if !ok {
+			t.Errorf("expect pull to be ok")
+			t.FailNow()
+		}
+		counts[task.FairnessKey]++
+	}
+	if counts["high"] != 14 || counts["medium"] != 4 || counts["low"] != 2 {
+		t.Errorf("expect shares to be 14/4/2 after two rounds, was %d/%d/%d", counts["high"], counts["medium"], counts["low"])
+		t.Fail()
+	}
+
+	for x := 0; x < 40; x++ {
+		task, ok := rq.Pull()
+		if !ok {
+			t.Errorf("expect pull to be ok")
+			t.FailNow()
+		}
+		if task == nil {
+			t.Errorf("expect pull task to not be nil")
+			t.FailNow()
+		}
+	}
+	if rq.Len() != 0 {
+		t.Errorf("expect tq length to be 0, was %d", rq.Len())
+		t.Fail()
+	}
+	if _, ok := rq.Pull(); ok {
+		t.Errorf("expect pull from an empty queue to not be ok")
+		t.Fail()
+	}
+}
+
+func Test_DeficitRoundRobinTaskQueue_workerTime(t *testing.T) {
+	rq := NewDeficitRoundRobinTaskQueue(CostModelWorkerTime)
+
+	for x := 0; x < 100; x++ {
+		rq.Push(Task{ID: NewUUID(), FairnessKey: "slow", Fairness: 1, WorkDuration: 400 * time.Millisecond})
+		rq.Push(Task{ID: NewUUID(), FairnessKey: "fast", Fairness: 1, WorkDuration: 100 * time.Millisecond})
+	}
+
+	workerTime := make(map[string]time.Duration)
+	for x := 0; x < 50; x++ {
+		task, ok := rq.Pull()
+		if !ok {
+			t.Errorf("expect pull to be ok")
+			t.FailNow()
+		}
+		workerTime[task.FairnessKey] += task.WorkDuration
+	}
+	if workerTime["fast"] != 4*time.Second || workerTime["slow"] != 4*time.Second {
+		t.Errorf("expect fast/slow worker time to be 4s/4s, was %v/%v", workerTime["fast"], workerTime["slow"])
+		t.Fail()
+	}
+}
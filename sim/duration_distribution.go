@@ -0,0 +1,130 @@
+package sim
+
+import (
+	"math"
+	"math/rand/v2"
+	"time"
+)
+
+// DurationDistribution is a distribution task work durations are drawn from.
+type DurationDistribution interface {
+	Sample(r *rand.Rand) time.Duration
+}
+
+// NormalDuration is a normal distribution of durations.
+//
+// This is the original task duration behavior of the simulation; note
+// that it can produce negative durations, which complete immediately.
+type NormalDuration struct {
+	Mean   time.Duration
+	StdDev time.Duration
+}
+
+// Sample implements [DurationDistribution].
+func (nd NormalDuration) Sample(r *rand.Rand) time.Duration {
+	return time.Duration(RandomNormal(r, float64(nd.Mean), float64(nd.StdDev)))
+}
+
+// LogNormalDuration is a log-normal distribution of durations, i.e. durations whose
+// logarithm is normally distributed with a mean of log(Median) and a standard deviation of Sigma.
+//
+// The mean of the distribution is Median * exp(Sigma^2 / 2).
+type LogNormalDuration struct {
+	Median time.Duration
+	Sigma  float64
+}
+
+// LogNormalDurationFromMean returns a log-normal distribution with a given mean and standard deviation.
+func LogNormalDurationFromMean(mean, stdDev time.Duration) LogNormalDuration {
+	sigma := math.Sqrt(math.Log(1 + math.Pow(float64(stdDev)/float64(mean), 2)))
+	return LogNormalDuration{
+		Median: time.Duration(float64(mean) / math.Exp(sigma*sigma/2)),
+		Sigma:  sigma,
+	}
+}
+
+// Sample implements [DurationDistribution].
+func (ld LogNormalDuration) Sample(r *rand.Rand) time.Duration {
+	return time.Duration(float64(ld.Median) * math.Exp(ld.Sigma*r.NormFloat64()))
+}
+
+// ExponentialDuration is an exponential distribution of durations with a given mean.
+type ExponentialDuration struct {
+	Mean time.Duration
+}
+
+// Sample implements [DurationDistribution].
+func (ed ExponentialDuration) Sample(r *rand.Rand) time.Duration {
+	return time.Duration(r.ExpFloat64() * float64(ed.Mean))
+}
+
+// ParetoDuration is a (type I) pareto distribution of durations, i.e. a heavy tailed
+// distribution with a minimum of Scale where the tail gets heavier as Shape approaches zero.
+//
+// The mean is Scale * Shape / (Shape - 1) for shapes over one, and is unbounded otherwise;
+// Max caps the samples if set.
+type ParetoDuration struct {
+	Scale time.Duration
+	Shape float64
+	Max   time.Duration
+}
+
+// Sample implements [DurationDistribution].
+func (pd ParetoDuration) Sample(r *rand.Rand) time.Duration {
+	// 1 - Float64() is in (0, 1] so we never divide by zero.
+	sample := float64(pd.Scale) / math.Pow(1-r.Float64(), 1/pd.Shape)
+	if pd.Max > 0 && sample > float64(pd.Max) {
+		return pd.Max
+	}
+	return time.Duration(sample)
+}
+
+// BimodalDuration is a mix of a fast and a slow distribution, where
+// a given fraction of the samples are drawn from the slow distribution.
+type BimodalDuration struct {
+	Fast            DurationDistribution
+	Slow            DurationDistribution
+	SlowProbability float64
+}
+
+// Sample implements [DurationDistribution].
+func (bd BimodalDuration) Sample(r *rand.Rand) time.Duration {
+	if r.Float64() < bd.SlowProbability {
+		return bd.Slow.Sample(r)
+	}
+	return bd.Fast.Sample(r)
+}
+
+// EmpiricalDuration is a distribution described by a histogram, e.g. as measured from production.
+//
+// Buckets must be sorted by upper bound; a bucket covers the durations from the previous
+// bucket's upper bound (or zero) to its own upper bound, and samples are uniformly
+// distributed within a bucket.
+type EmpiricalDuration struct {
+	Buckets []EmpiricalBucket
+}
+
+// EmpiricalBucket is a histogram bucket of an [EmpiricalDuration].
+type EmpiricalBucket struct {
+	UpperBound time.Duration
+	Weight     float64
+}
+
+// Sample implements [DurationDistribution].
+func (ed EmpiricalDuration) Sample(r *rand.Rand) time.Duration {
+	var total float64
+	for _, b := range ed.Buckets {
+		total += b.Weight
+	}
+	nf := r.Float64() * total
+	var accum float64
+	var lowerBound time.Duration
+	for _, b := range ed.Buckets {
+		accum += b.Weight
+		if nf < accum {
+			return lowerBound + time.Duration(r.Float64()*float64(b.UpperBound-lowerBound))
+		}
+		lowerBound = b.UpperBound
+	}
+	return lowerBound
+}
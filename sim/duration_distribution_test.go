@@ -0,0 +1,64 @@
+package sim
+
+import (
+	"math"
+	"math/rand/v2"
+	"testing"
+	"time"
+)
+
+func Test_DurationDistribution_means(t *testing.T) {
+	testCases := []struct {
+		Name         string
+		Distribution DurationDistribution
+	}{
+		{Name: "normal", Distribution: NormalDuration{Mean: 100 * time.Millisecond, StdDev: 10 * time.Millisecond}},
+		{Name: "lognormal", Distribution: LogNormalDurationFromMean(100*time.Millisecond, 50*time.Millisecond)},
+		{Name: "exponential", Distribution: ExponentialDuration{Mean: 100 * time.Millisecond}},
+		{Name: "pareto", Distribution: ParetoDuration{Scale: 60 * time.Millisecond, Shape: 2.5}},
+		{Name: "bimodal", Distribution: BimodalDuration{
+			Fast:            NormalDuration{Mean: 50 * time.Millisecond},
+			Slow:            NormalDuration{Mean: 550 * time.Millisecond},
+			SlowProbability: 0.1,
+		}},
+		{Name: "empirical", Distribution: EmpiricalDuration{Buckets: []EmpiricalBucket{
+			{UpperBound: 100 * time.Millisecond, Weight: 1},
+			{UpperBound: 200 * time.Millisecond, Weight: 1},
+		}}},
+	}
+	for _, tc := range testCases {
+		r := rand.New(rand.NewPCG(123, 123))
+		samples := make([]time.Duration, 100_000)
+		for x := range samples {
+			samples[x] = tc.Distribution.Sample(r)
+		}
+		mean := AvgDurations(samples)
+		if math.Abs(float64(mean-100*time.Millisecond)) > float64(5*time.Millisecond) {
+			t.Errorf("%s: expect mean to be within 5ms of 100ms, was %v", tc.Name, mean)
+			t.Fail()
+		}
+	}
+}
+
+func Test_SimulationConfig_TaskDurationFor(t *testing.T) {
+	global := ExponentialDuration{Mean: time.Second}
+	byKey := ExponentialDuration{Mean: 2 * time.Second}
+	byPriority := ExponentialDuration{Mean: 3 * time.Second}
+	sc := SimulationConfig{
+		TaskDuration:              global,
+		TaskDurationByFairnessKey: map[string]DurationDistribution{"slow": byKey},
+		TaskDurationByPriority:    map[Priority]DurationDistribution{P4: byPriority},
+	}
+	if sc.TaskDurationFor("slow", P4) != byKey {
+		t.Errorf("expect the fairness key override to take precedence")
+		t.Fail()
+	}
+	if sc.TaskDurationFor("fast", P4) != byPriority {
+		t.Errorf("expect the priority override to apply")
+		t.Fail()
+	}
+	if sc.TaskDurationFor("fast", P2) != global {
+		t.Errorf("expect the global distribution to apply")
+		t.Fail()
+	}
+}
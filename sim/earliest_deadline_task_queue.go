@@ -0,0 +1,106 @@
+package sim
+
+import (
+	"math/rand/v2"
+	"slices"
+)
+
+// NewEarliestDeadlineTaskQueue returns a new earliest deadline first task queue.
+//
+// The "edf" task queue serves the task with the earliest deadline, oldest first within
+// a fairness key. When the tasks at the head of several fairness keys share the earliest
+// deadline (including when none of them have a deadline), a fairness key is chosen at
+// random in proportion to its fairness weight, as with the "fairness" task queue.
+//
+// Tasks without a deadline are served after every task with one, and priority is
+// ignored other than through the deadlines of each priority.
+func NewEarliestDeadlineTaskQueue(r *rand.Rand) TaskQueue {
+	return &earliestDeadlineTaskQueue{
+		storage:            make(map[string]*Heap[*Task]),
+		fairnessKeyWeights: make(map[string]float64),
+		inFlight:           make(inFlight),
+		r:                  r,
+	}
+}
+
+type earliestDeadlineTaskQueue struct {
+	len                int
+	storage            map[string]*Heap[*Task]
+	keys               []string // the fairness keys with tasks queued, sorted
+	fairnessKeyWeights map[string]float64
+	inFlight
+	r *rand.Rand
+}
+
+// deadlineBefore returns if a task is due before another, where tasks without a
+// deadline are due after every task with one, and otherwise the older task first.
+func deadlineBefore(a, b *Task) bool {
+	switch {
+	case a.Deadline.IsZero() != b.Deadline.IsZero():
+		return b.Deadline.IsZero()
+	case !a.Deadline.Equal(b.Deadline):
+		return a.Deadline.Before(b.Deadline)
+	default:
+		return a.CreatedUTC.Before(b.CreatedUTC)
+	}
+}
+
+func (q *earliestDeadlineTaskQueue) Len() int {
+	return q.len
+}
+
+func (q *earliestDeadlineTaskQueue) Push(t Task) {
+	tasks, ok := q.storage[t.FairnessKey]
+	if !ok {
+		tasks = NewHeap(deadlineBefore)
+		q.storage[t.FairnessKey] = tasks
+		index, _ := slices.BinarySearch(q.keys, t.FairnessKey)
+		q.keys = slices.Insert(q.keys, index, t.FairnessKey)
+	}
+	tasks.Push(&t)
+	q.fairnessKeyWeights[t.FairnessKey] = fairnessWeightOf(&t)
+	q.len++
+}
+
+func (q *earliestDeadlineTaskQueue) Pull() (task *Task, ok bool) {
+	return q.PullFiltered(allowAllFairnessKeys)
+}
+
+// PullFiltered implements [FilteredTaskQueue].
+func (q *earliestDeadlineTaskQueue) PullFiltered(allow func(fairnessKey string) bool) (task *Task, ok bool) {
+	var earliest *Task
+	candidates := make(map[string]float64)
+	for _, key := range q.keys {
+		if !allow(key) {
+			continue
+		}
+		head, _ := q.storage[key].Peek()
+		switch {
+		case earliest == nil || deadlineBefore(head, earliest) && !head.Deadline.Equal(earliest.Deadline):
+			earliest = head
+			clear(candidates)
+			candidates[key] = q.fairnessKeyWeights[key]
+		case head.Deadline.Equal(earliest.Deadline):
+			candidates[key] = q.fairnessKeyWeights[key]
+		}
+	}
+	if len(candidates) == 0 {
+		return
+	}
+	fairnessKey := RandomKeyByWeight(q.r, q.keys, candidates)
+	task, ok = q.storage[fairnessKey].Pop()
+	if q.storage[fairnessKey].Len() == 0 {
+		delete(q.storage, fairnessKey)
+		if index, found := slices.BinarySearch(q.keys, fairnessKey); found {
+			q.keys = slices.Delete(q.keys, index, index+1)
+		}
+	}
+	q.len--
+	q.inFlight.dispatched(task)
+	return
+}
+
+// OnComplete implements [CompletionObserver].
+func (q *earliestDeadlineTaskQueue) OnComplete(t *Task) {
+	q.inFlight.completed(t)
+}
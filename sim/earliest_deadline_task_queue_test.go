@@ -0,0 +1,64 @@
+package sim
+
+import (
+	"math/rand/v2"
+	"testing"
+	"time"
+)
+
+func Test_EarliestDeadlineTaskQueue(t *testing.T) {
+	rq := NewEarliestDeadlineTaskQueue(rand.New(rand.NewPCG(1, 2)))
+	start := SimulatedStartTime
+
+	rq.Push(Task{ID: NewUUID(), FairnessKey: "a", CreatedUTC: start, Priority: P4})
+	rq.Push(Task{ID: NewUUID(), FairnessKey: "a", CreatedUTC: start, Priority: P3, Deadline: start.Add(time.Minute)})
+	rq.Push(Task{ID: NewUUID(), FairnessKey: "b", CreatedUTC: start.Add(time.Second), Priority: P1, Deadline: start.Add(5 * time.Second)})
+	rq.Push(Task{ID: NewUUID(), FairnessKey: "b", CreatedUTC: start, Priority: P2, Deadline: start.Add(30 * time.Second)})
+
+	if rq.Len() != 4 {
+		t.Errorf("expect tq length to be 4, was %d", rq.Len())
+		t.Fail()
+	}
+
+	// tasks without a deadline are served last.
+	expected := []Priority{P1, P2, P3, P4}
+	for x, expectedPriority := range expected {
+		task, ok := rq.Pull()
+		if !ok {
+			t.Errorf("expect pull to be ok")
+			t.FailNow()
+		}
+		if task.Priority != expectedPriority {
+			t.Errorf("expect pull %d to be %v, was %v", x, expectedPriority, task.Priority)
+			t.Fail()
+		}
+	}
+	if _, ok := rq.Pull(); ok || rq.Len() != 0 {
+		t.Errorf("expect tq to be empty, was %d", rq.Len())
+		t.Fail()
+	}
+}
+
+func Test_EarliestDeadlineTaskQueue_ties(t *testing.T) {
+	rq := NewEarliestDeadlineTaskQueue(rand.New(rand.NewPCG(1, 2)))
+	deadline := SimulatedStartTime.Add(time.Minute)
+
+	// tasks with the same deadline are shared between fairness keys by weight.
+	for x := 0; x < 1000; x++ {
+		rq.Push(Task{ID: NewUUID(), FairnessKey: "high", Fairness: 3, Deadline: deadline})
+		rq.Push(Task{ID: NewUUID(), FairnessKey: "low", Fairness: 1, Deadline: deadline})
+	}
+	counts := make(map[string]int)
+	for x := 0; x < 1000; x++ {
+		task, ok := rq.Pull()
+		if !ok {
+			t.Errorf("expect pull to be ok")
+			t.FailNow()
+		}
+		counts[task.FairnessKey]++
+	}
+	if counts["high"] < 700 || counts["high"] > 800 {
+		t.Errorf("expect about 750 of 1000 pulls to be from high, was %d", counts["high"])
+		t.Fail()
+	}
+}
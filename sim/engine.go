@@ -0,0 +1,38 @@
+package sim
+
+import "fmt"
+
+// Engine is the method by which the simulation advances time.
+type Engine int
+
+const (
+	// EngineTick advances the simulation in fixed tick interval steps, processing
+	// arrivals, dispatches and completions in batches at the end of each tick.
+	EngineTick Engine = iota
+	// EngineEvent is a discrete event engine that advances the simulation directly
+	// to the next arrival, dispatch or completion, such that queueing delays are exact.
+	EngineEvent
+)
+
+// ParseEngine parses an engine from its string form.
+func ParseEngine(value string) (Engine, error) {
+	switch value {
+	case "tick", "":
+		return EngineTick, nil
+	case "event":
+		return EngineEvent, nil
+	default:
+		return EngineTick, fmt.Errorf("invalid engine: %q", value)
+	}
+}
+
+func (e Engine) String() string {
+	switch e {
+	case EngineTick:
+		return "tick"
+	case EngineEvent:
+		return "event"
+	default:
+		return ""
+	}
+}
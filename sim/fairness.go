@@ -0,0 +1,93 @@
+package sim
+
+import (
+	"maps"
+	"math"
+	"time"
+)
+
+// FairnessResults summarize how fairly the fairness keys were served.
+type FairnessResults struct {
+	// JainIndex is Jain's fairness index over the tasks processed for each fairness key divided
+	// by its fairness weight; it is 1 if every key was served in proportion to its weight, down
+	// to 1/n if a single one of n keys was served.
+	//
+	// Only keys that had tasks are included, and a key with less demand than its share lowers
+	// the index even if it was served every task it had.
+	JainIndex float64
+
+	// ShareByFairnessKey is each fairness key's share of the tasks processed.
+	ShareByFairnessKey map[string]float64
+	// ConfiguredShareByFairnessKey is each fairness key's share of the fairness weights
+	// of the keys that had tasks.
+	ConfiguredShareByFairnessKey map[string]float64
+	// ShareByFairnessPath is the share of the tasks processed under each parent path of
+	// hierarchical fairness keys, see [FairnessPathParents].
+	ShareByFairnessPath map[string]float64
+	// MaxShareDeviation is the largest absolute difference between a fairness key's
+	// share and its configured share.
+	MaxShareDeviation float64
+
+	// LongestStarvationByFairnessKey is the longest time each fairness key had tasks queued
+	// without one being dispatched; with the tick engine this is at least the tick interval.
+	LongestStarvationByFairnessKey map[string]time.Duration
+	// LongestStarvation is the longest starvation of any fairness key.
+	LongestStarvation time.Duration
+	// LongestStarvationFairnessKey is the fairness key with the longest starvation.
+	LongestStarvationFairnessKey string
+}
+
+// ShareDeviation returns the difference between a fairness key's share and its configured share.
+func (fr FairnessResults) ShareDeviation(fairnessKey string) float64 {
+	return fr.ShareByFairnessKey[fairnessKey] - fr.ConfiguredShareByFairnessKey[fairnessKey]
+}
+
+// newFairnessResults returns the fairness of the tasks processed for each of the fairness
+// keys that had tasks, given their fairness weights.
+//
+// Keys with a weight that is not positive are weighted as 1.
+func newFairnessResults(countByFairnessKey map[string]int, weights map[string]float64) FairnessResults {
+	fr := FairnessResults{
+		ShareByFairnessKey:           make(map[string]float64),
+		ConfiguredShareByFairnessKey: make(map[string]float64),
+		ShareByFairnessPath:          make(map[string]float64),
+	}
+	var totalCount int
+	var totalWeight, sum, sumOfSquares float64
+	weights = maps.Clone(weights)
+	for key, weight := range weights {
+		if weight <= 0 {
+			weights[key] = 1
+		}
+		totalCount += countByFairnessKey[key]
+		totalWeight += weights[key]
+	}
+	if totalCount == 0 {
+		return fr
+	}
+	for _, key := range sortedMapKeys(weights) {
+		count, weight := countByFairnessKey[key], weights[key]
+		normalized := float64(count) / weight
+		sum += normalized
+		sumOfSquares += normalized * normalized
+		fr.ShareByFairnessKey[key] = float64(count) / float64(totalCount)
+		for _, path := range FairnessPathParents(key) {
+			fr.ShareByFairnessPath[path] += fr.ShareByFairnessKey[key]
+		}
+		fr.ConfiguredShareByFairnessKey[key] = weight / totalWeight
+		fr.MaxShareDeviation = max(fr.MaxShareDeviation, math.Abs(fr.ShareDeviation(key)))
+	}
+	fr.JainIndex = sum * sum / (float64(len(weights)) * sumOfSquares)
+	return fr
+}
+
+// setStarvation sets the longest starvation of each fairness key.
+func (fr *FairnessResults) setStarvation(longestByFairnessKey map[string]time.Duration) {
+	fr.LongestStarvationByFairnessKey = longestByFairnessKey
+	for _, key := range sortedMapKeys(longestByFairnessKey) {
+		if longest := longestByFairnessKey[key]; longest > fr.LongestStarvation {
+			fr.LongestStarvation = longest
+			fr.LongestStarvationFairnessKey = key
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package sim
+
+import "time"
+
+// fairnessStats tracks the queued tasks of each fairness key over time to find
+// the longest intervals a key had tasks queued but none were dispatched, i.e.
+// how long each key was starved for.
+type fairnessStats struct {
+	queued       map[string]int
+	nonEmptyAt   map[string]time.Time
+	dispatchedAt map[string]time.Time
+	weights      map[string]float64
+	longest      map[string]time.Duration
+
+	bucketStart   time.Time
+	bucketLongest map[string]time.Duration
+	// bucketWeights are the weights of the fairness keys that had tasks queued during the bucket.
+	bucketWeights map[string]float64
+}
+
+func newFairnessStats(start time.Time) *fairnessStats {
+	return &fairnessStats{
+		queued:        make(map[string]int),
+		nonEmptyAt:    make(map[string]time.Time),
+		dispatchedAt:  make(map[string]time.Time),
+		weights:       make(map[string]float64),
+		longest:       make(map[string]time.Duration),
+		bucketStart:   start,
+		bucketLongest: make(map[string]time.Duration),
+		bucketWeights: make(map[string]float64),
+	}
+}
+
+func (fs *fairnessStats) pushed(now time.Time, fairnessKey string, fairness float64) {
+	if fs.queued[fairnessKey] == 0 {
+		fs.nonEmptyAt[fairnessKey] = now
+	}
+	fs.queued[fairnessKey]++
+	fs.weights[fairnessKey] = fairness
+	fs.bucketWeights[fairnessKey] = fairness
+}
+
+func (fs *fairnessStats) dispatched(now time.Time, fairnessKey string) {
+	fs.starved(now, fairnessKey)
+	fs.queued[fairnessKey]--
+	fs.dispatchedAt[fairnessKey] = now
+}
+
+// starved records the time a non-empty fairness key has gone without a dispatch as of now.
+func (fs *fairnessStats) starved(now time.Time, fairnessKey string) {
+	since := fs.nonEmptyAt[fairnessKey]
+	if dispatchedAt := fs.dispatchedAt[fairnessKey]; dispatchedAt.After(since) {
+		since = dispatchedAt
+	}
+	fs.longest[fairnessKey] = max(fs.longest[fairnessKey], now.Sub(since))
+	if fs.bucketStart.After(since) {
+		since = fs.bucketStart
+	}
+	fs.bucketLongest[fairnessKey] = max(fs.bucketLongest[fairnessKey], now.Sub(since))
+}
+
+// closeBucket returns the longest starvation of each fairness key during the bucket, and the
+// weights of the fairness keys that had tasks queued during it, and starts the next bucket.
+func (fs *fairnessStats) closeBucket(now time.Time) (longest map[string]time.Duration, weights map[string]float64) {
+	fs.starvedUntil(now)
+	longest, weights = fs.bucketLongest, fs.bucketWeights
+	fs.bucketStart = now
+	fs.bucketLongest = make(map[string]time.Duration)
+	fs.bucketWeights = make(map[string]float64)
+	for key, count := range fs.queued {
+		if count > 0 {
+			fs.bucketWeights[key] = fs.weights[key]
+		}
+	}
+	return
+}
+
+// starvedUntil records the starvation of the fairness keys that still have tasks queued.
+func (fs *fairnessStats) starvedUntil(now time.Time) {
+	for key, count := range fs.queued {
+		if count > 0 {
+			fs.starved(now, key)
+		}
+	}
+}
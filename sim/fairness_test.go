@@ -0,0 +1,72 @@
+package sim
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func Test_newFairnessResults(t *testing.T) {
+	fair := newFairnessResults(map[string]int{"a": 300, "b": 100}, map[string]float64{"a": 3, "b": 1})
+	if math.Abs(fair.JainIndex-1) > 1e-9 || fair.MaxShareDeviation > 1e-9 {
+		t.Errorf("expect keys served in proportion to their weights to have a jain index of 1 and no deviation, was %v and %v", fair.JainIndex, fair.MaxShareDeviation)
+		t.Fail()
+	}
+
+	weights := map[string]float64{"a": 1, "b": 0}
+	unfair := newFairnessResults(map[string]int{"a": 100}, weights)
+	if math.Abs(unfair.JainIndex-0.5) > 1e-9 {
+		t.Errorf("expect a single one of two keys served to have a jain index of 0.5, was %v", unfair.JainIndex)
+		t.Fail()
+	}
+	if unfair.ConfiguredShareByFairnessKey["b"] != 0.5 || unfair.ShareByFairnessKey["b"] != 0 {
+		t.Errorf("expect a key with a weight of 0 to be weighted as 1, was %v", unfair.ConfiguredShareByFairnessKey)
+		t.Fail()
+	}
+	if math.Abs(unfair.MaxShareDeviation-0.5) > 1e-9 || unfair.ShareDeviation("b") != -0.5 {
+		t.Errorf("expect a share deviation of 0.5, was %v", unfair.MaxShareDeviation)
+		t.Fail()
+	}
+	if weights["b"] != 0 {
+		t.Errorf("expect the weights passed in to be unchanged, was %v", weights)
+		t.Fail()
+	}
+
+	if empty := newFairnessResults(nil, map[string]float64{"a": 1}); empty.JainIndex != 0 {
+		t.Errorf("expect no tasks processed to have a jain index of 0, was %v", empty.JainIndex)
+		t.Fail()
+	}
+}
+
+func Test_fairnessStats_starvation(t *testing.T) {
+	start := SimulatedStartTime
+	fs := newFairnessStats(start)
+	fs.pushed(start, "a", 1)
+	fs.pushed(start.Add(time.Second), "a", 1)
+	fs.pushed(start.Add(time.Second), "b", 1)
+	fs.dispatched(start.Add(2*time.Second), "a")
+	fs.dispatched(start.Add(3*time.Second), "a")
+
+	// b is still queued when the bucket closes, so it is starved until then.
+	longest, weights := fs.closeBucket(start.Add(5 * time.Second))
+	if longest["a"] != 2*time.Second || longest["b"] != 4*time.Second {
+		t.Errorf("expect a starved for 2s and b for 4s, was %v", longest)
+		t.Fail()
+	}
+	if len(weights) != 2 {
+		t.Errorf("expect the weights of both keys, was %v", weights)
+		t.Fail()
+	}
+
+	// the next bucket only counts b's starvation since it started.
+	fs.dispatched(start.Add(6*time.Second), "b")
+	longest, weights = fs.closeBucket(start.Add(10 * time.Second))
+	if longest["b"] != time.Second || fs.longest["b"] != 5*time.Second {
+		t.Errorf("expect b starved for 1s in the bucket and 5s overall, was %v and %v", longest["b"], fs.longest["b"])
+		t.Fail()
+	}
+	if _, ok := weights["a"]; ok {
+		t.Errorf("expect no weight for a key without tasks queued in the bucket, was %v", weights)
+		t.Fail()
+	}
+}
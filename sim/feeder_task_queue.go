@@ -2,6 +2,7 @@ package sim
 
 import (
 	"math/rand/v2"
+	"slices"
 	"time"
 )
 
@@ -10,6 +11,13 @@ type Limit struct {
 	Quantum time.Duration
 }
 
+// RateLimitSetter is implemented by task queues whose per fairness key
+// rate limits can be changed while the simulation runs.
+type RateLimitSetter interface {
+	RateLimits() map[string]Limit
+	SetRateLimits(rateLimitsByFairnessKey map[string]Limit)
+}
+
 // NewFeederTaskQueue returns a new feeder task queue with a given set of settings.
 //
 // The "feeder" task queue type tries to honor absolute rate limits across the fairness keys
@@ -17,23 +25,32 @@ type Limit struct {
 //
 // The net effect of this is strictly there is an upperbound to throughput for the system, which
 // may be desirable to limit the impact of bursts on downstream systems.
-func NewFeederTaskQueue(r *rand.Rand, c Clock, rateLimitsByFairnessKey map[string]Limit) TaskQueue {
-	rateLimiters := make(map[string]RateLimiter, len(rateLimitsByFairnessKey))
-	for key, lim := range rateLimitsByFairnessKey {
-		rateLimiters[key] = NewRateLimiter(c, lim.Actions, lim.Quantum)
-	}
-	return &feederTaskQueue{
-		fairnessKeyRateLimiters: rateLimiters,
-		storage:                 make(map[string]*Queue[*Task]),
-		r:                       r,
+//
+// With the [CostModelWorkerTime] cost model the limits are in worker-seconds rather than tasks,
+// e.g. a limit of 100 actions per second allows a fairness key to occupy 100 task slots continuously.
+func NewFeederTaskQueue(r *rand.Rand, c Clock, costModel CostModel, rateLimitsByFairnessKey map[string]Limit) TaskQueue {
+	q := &feederTaskQueue{
+		storage:   make(map[string]*Queue[*Task]),
+		costModel: costModel,
+		inFlight:  make(inFlight),
+		r:         r,
+		c:         c,
 	}
+	q.SetRateLimits(rateLimitsByFairnessKey)
+	return q
 }
 
 type feederTaskQueue struct {
-	len                     int
-	storage                 map[string]*Queue[*Task]
+	len     int
+	storage map[string]*Queue[*Task]
+	// keys are the fairness keys of the storage, sorted.
+	keys                    []string
+	fairnessKeyLimits       map[string]Limit
 	fairnessKeyRateLimiters map[string]RateLimiter
-	r                       *rand.Rand
+	costModel               CostModel
+	inFlight
+	r *rand.Rand
+	c Clock
 }
 
 func (q *feederTaskQueue) Len() int {
@@ -43,27 +60,81 @@ func (q *feederTaskQueue) Len() int {
 func (q *feederTaskQueue) Push(t Task) {
 	if q.storage[t.FairnessKey] == nil {
 		q.storage[t.FairnessKey] = &Queue[*Task]{}
+		index, _ := slices.BinarySearch(q.keys, t.FairnessKey)
+		q.keys = slices.Insert(q.keys, index, t.FairnessKey)
 	}
 	q.storage[t.FairnessKey].Push(&t)
 	q.len++
 }
 
 func (q *feederTaskQueue) Pull() (task *Task, ok bool) {
-	key, ok := q.getKey()
+	return q.PullFiltered(allowAllFairnessKeys)
+}
+
+// PullFiltered implements [FilteredTaskQueue].
+func (q *feederTaskQueue) PullFiltered(allow func(fairnessKey string) bool) (task *Task, ok bool) {
+	key, ok := q.getKey(allow)
 	if !ok {
 		return
 	}
+	task, ok = q.storage[key].Pop()
 	if rl, ok := q.fairnessKeyRateLimiters[key]; ok {
-		rl.Commit()
+		rl.CommitN(q.costModel.Estimate(task))
 	}
-	task, ok = q.storage[key].Pop()
+	q.inFlight.dispatched(task)
 	q.len--
 	return
 }
 
-func (q *feederTaskQueue) getKey() (key string, ok bool) {
-	for fairnessKey := range q.storage {
-		if q.storage[fairnessKey].Len() == 0 {
+// OnComplete implements [CompletionObserver].
+func (q *feederTaskQueue) OnComplete(t *Task) {
+	q.inFlight.completed(t)
+	if q.costModel == CostModelTaskCount {
+		return
+	}
+	if rl, ok := q.fairnessKeyRateLimiters[t.FairnessKey]; ok {
+		rl.CommitN(q.costModel.Correction(t))
+	}
+}
+
+// RateLimits implements [RateLimitSetter].
+func (q *feederTaskQueue) RateLimits() map[string]Limit {
+	output := make(map[string]Limit, len(q.fairnessKeyLimits))
+	for key, lim := range q.fairnessKeyLimits {
+		output[key] = lim
+	}
+	return output
+}
+
+// SetRateLimits implements [RateLimitSetter].
+//
+// Fairness keys whose limit is unchanged keep their rate limiter state, fairness
+// keys without a limit are no longer limited.
+func (q *feederTaskQueue) SetRateLimits(rateLimitsByFairnessKey map[string]Limit) {
+	limits := make(map[string]Limit, len(rateLimitsByFairnessKey))
+	rateLimiters := make(map[string]RateLimiter, len(rateLimitsByFairnessKey))
+	for key, lim := range rateLimitsByFairnessKey {
+		limits[key] = lim
+		if rl, ok := q.fairnessKeyRateLimiters[key]; ok && q.fairnessKeyLimits[key] == lim {
+			rateLimiters[key] = rl
+			continue
+		}
+		rateLimiters[key] = NewRateLimiter(q.c, lim.Actions, lim.Quantum)
+	}
+	q.fairnessKeyLimits = limits
+	q.fairnessKeyRateLimiters = rateLimiters
+}
+
+// getKey returns the first fairness key allowed with a task that its rate limit allows,
+// starting from a random key such that no key is favored.
+func (q *feederTaskQueue) getKey(allow func(fairnessKey string) bool) (key string, ok bool) {
+	if len(q.keys) == 0 {
+		return
+	}
+	offset := q.r.IntN(len(q.keys))
+	for index := range q.keys {
+		fairnessKey := q.keys[(offset+index)%len(q.keys)]
+		if q.storage[fairnessKey].Len() == 0 || !allow(fairnessKey) {
 			continue
 		}
 		if rl, ok := q.fairnessKeyRateLimiters[fairnessKey]; ok && !rl.Allow() {
@@ -75,3 +146,14 @@ func (q *feederTaskQueue) getKey() (key string, ok bool) {
 	}
 	return
 }
+
+// Drain implements [Drainer].
+func (q *feederTaskQueue) Drain() (output []*Task) {
+	for _, key := range q.keys {
+		lane := q.storage[key]
+		output = append(output, lane.Values()...)
+		lane.Clear()
+	}
+	q.len = 0
+	return
+}
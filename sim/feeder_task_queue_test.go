@@ -9,7 +9,7 @@ import (
 func Test_FeederTaskQueue(t *testing.T) {
 	c := NewSimulatedClock(time.Now())
 	r := rand.NewPCG(123, 123)
-	rq := NewFeederTaskQueue(rand.New(r), c, map[string]Limit{
+	rq := NewFeederTaskQueue(rand.New(r), c, CostModelTaskCount, map[string]Limit{
 		"high":   {Actions: 1000, Quantum: time.Second},
 		"medium": {Actions: 500, Quantum: time.Second},
 		"low":    {Actions: 100, Quantum: time.Second},
@@ -0,0 +1,60 @@
+package sim
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// FieldError is a validation error for a specific field of a scenario, where
+// the field is a path such as "tenants[1].taskDuration.shape".
+type FieldError struct {
+	Field   string
+	Message string
+}
+
+func (fe FieldError) Error() string {
+	return fe.Field + ": " + fe.Message
+}
+
+// fieldErrors collects validation errors.
+type fieldErrors []error
+
+func (fe *fieldErrors) add(field, format string, args ...any) {
+	*fe = append(*fe, FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+func (fe *fieldErrors) join(err error) {
+	if err != nil {
+		*fe = append(*fe, err)
+	}
+}
+
+func (fe *fieldErrors) positive(field string, value float64) {
+	if value <= 0 {
+		fe.add(field, "must be positive")
+	}
+}
+
+func (fe *fieldErrors) nonNegative(field string, value float64) {
+	if value < 0 {
+		fe.add(field, "must not be negative")
+	}
+}
+
+func (fe fieldErrors) err() error {
+	return errors.Join(fe...)
+}
+
+// duration parses an optional duration string.
+func (fe *fieldErrors) duration(field, value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		fe.add(field, "must be a duration (e.g. \"500ms\"), was %q", value)
+		return 0
+	}
+	return parsed
+}
@@ -0,0 +1,201 @@
+package sim
+
+import "strings"
+
+// FairnessPathSeparator separates the levels of a hierarchical fairness key, e.g. "acct1/merchantA/export".
+const FairnessPathSeparator = "/"
+
+// FairnessPathParents returns the parent paths of a hierarchical fairness key, outermost first,
+// e.g. "acct1" and "acct1/merchantA" for "acct1/merchantA/export", or none for a flat key.
+func FairnessPathParents(fairnessKey string) (parents []string) {
+	for offset := 0; ; {
+		index := strings.Index(fairnessKey[offset:], FairnessPathSeparator)
+		if index < 0 {
+			return
+		}
+		offset += index
+		parents = append(parents, fairnessKey[:offset])
+		offset += len(FairnessPathSeparator)
+	}
+}
+
+// NewHierarchicalFairTaskQueue returns a new hierarchical fair task queue.
+//
+// The "hfq" task queue treats fairness keys as paths, e.g. "acct1/merchantA/export", and
+// shares dispatches by weight at each level of the paths: first between the accounts, then
+// between the merchants of the account served, and so on down to a fifo lane per fairness key.
+// A parent's share is split between its children, so a parent with many children can't claim
+// more than its own share, unlike with the flat fair task queues.
+//
+// At each level the children with tasks queued are served in order of a virtual pass, which
+// advances by the cost of each task served divided by the child's weight (start-time fair
+// queueing), such that backlogged children receive shares in proportion to their weights
+// deterministically. Idle children do not bank credit.
+//
+// The weight of a path is its weight in pathWeights if set, otherwise the fairness weight of
+// the tasks of the fairness key equal to the path, otherwise 1. Tasks of a fairness key that is
+// also the parent of other keys (e.g. "acct1" and "acct1/merchantA") are served as one more child
+// of the path with a weight of 1.
+//
+// Costs are estimated with the cost model when a task is pulled and are not corrected when it
+// completes. Priority is ignored; tasks are served in arrival order within a fairness key.
+func NewHierarchicalFairTaskQueue(costModel CostModel, pathWeights map[string]float64) TaskQueue {
+	return &hierarchicalFairTaskQueue{
+		costModel:   costModel,
+		pathWeights: pathWeights,
+		root:        newHierarchicalNode("", nil, 1),
+		inFlight:    make(inFlight),
+	}
+}
+
+type hierarchicalFairTaskQueue struct {
+	costModel   CostModel
+	pathWeights map[string]float64
+	root        *hierarchicalNode
+	seq         uint64
+	inFlight
+}
+
+// hierarchicalNode is a path of the fairness keys, or the lane of the tasks of a fairness key.
+type hierarchicalNode struct {
+	path   string
+	weight float64
+	parent *hierarchicalNode
+	// children are the child paths by path, and the lane of the path's own tasks by "".
+	children map[string]*hierarchicalNode
+	// active are the children with tasks queued, by pass.
+	active *Heap[*hierarchicalNode]
+	// lane is set for the lane of a fairness key's tasks.
+	lane *Queue[*Task]
+	// queued is the number of tasks queued under the node.
+	queued int
+	// pass is the node's virtual time among its siblings, and virtualTime the pass of the
+	// child last served, which idle children catch up to when they have tasks queued again.
+	pass        float64
+	virtualTime float64
+	seq         uint64
+}
+
+func newHierarchicalNode(path string, parent *hierarchicalNode, weight float64) *hierarchicalNode {
+	return &hierarchicalNode{
+		path:     path,
+		weight:   weight,
+		parent:   parent,
+		children: make(map[string]*hierarchicalNode),
+		active:   NewHeap(hierarchicalNodeLess),
+	}
+}
+
+func hierarchicalNodeLess(i, j *hierarchicalNode) bool {
+	if i.pass != j.pass {
+		return i.pass < j.pass
+	}
+	return i.seq < j.seq
+}
+
+func (q *hierarchicalFairTaskQueue) Len() int {
+	return q.root.queued
+}
+
+func (q *hierarchicalFairTaskQueue) Push(t Task) {
+	node := q.root
+	for _, path := range append(FairnessPathParents(t.FairnessKey), t.FairnessKey) {
+		node = q.child(node, path)
+	}
+	if _, ok := q.pathWeights[t.FairnessKey]; !ok {
+		node.weight = fairnessWeightOf(&t)
+	}
+	leaf := q.child(node, "")
+	if leaf.lane == nil {
+		leaf.lane = &Queue[*Task]{}
+	}
+	leaf.lane.Push(&t)
+
+	for node = leaf; node.parent != nil; node = node.parent {
+		if node.queued == 0 {
+			node.pass = max(node.pass, node.parent.virtualTime)
+			q.activate(node)
+		}
+		node.queued++
+	}
+	q.root.queued++
+}
+
+// child returns the child of a node by path, adding it if it doesn't exist yet.
+func (q *hierarchicalFairTaskQueue) child(node *hierarchicalNode, path string) *hierarchicalNode {
+	child, ok := node.children[path]
+	if !ok {
+		weight, ok := q.pathWeights[path]
+		if !ok || path == "" {
+			weight = 1
+		}
+		child = newHierarchicalNode(path, node, weight)
+		node.children[path] = child
+	}
+	return child
+}
+
+func (q *hierarchicalFairTaskQueue) activate(node *hierarchicalNode) {
+	q.seq++
+	node.seq = q.seq
+	node.parent.active.Push(node)
+}
+
+func (q *hierarchicalFairTaskQueue) Pull() (task *Task, ok bool) {
+	return q.PullFiltered(allowAllFairnessKeys)
+}
+
+// PullFiltered implements [FilteredTaskQueue].
+func (q *hierarchicalFairTaskQueue) PullFiltered(allow func(fairnessKey string) bool) (task *Task, ok bool) {
+	var skipped []*hierarchicalNode
+	defer func() {
+		for _, node := range skipped {
+			node.parent.active.Push(node)
+		}
+	}()
+	path, found := q.next(q.root, allow, &skipped)
+	if !found {
+		return
+	}
+	task, ok = path[len(path)-1].lane.Pop()
+
+	cost := q.costModel.Estimate(task)
+	for _, node := range path {
+		_, _ = node.parent.active.Pop()
+		node.parent.virtualTime = node.pass
+		node.pass += cost / node.weight
+		if node.queued--; node.queued > 0 {
+			q.activate(node)
+		}
+	}
+	q.root.queued--
+	q.inFlight.dispatched(task)
+	return
+}
+
+// next returns the path from a node down to the lane to serve next of the fairness keys allowed,
+// where each node of the path is at the head of its parent's active children. Children passed
+// over because none of their fairness keys are allowed are popped into skipped to be restored.
+func (q *hierarchicalFairTaskQueue) next(node *hierarchicalNode, allow func(fairnessKey string) bool, skipped *[]*hierarchicalNode) (path []*hierarchicalNode, ok bool) {
+	for node.active.Len() > 0 {
+		child, _ := node.active.Peek()
+		if child.lane != nil {
+			// the lane of a fairness key is the child of the path equal to the key.
+			ok = allow(node.path)
+		} else {
+			path, ok = q.next(child, allow, skipped)
+		}
+		if ok {
+			path = append([]*hierarchicalNode{child}, path...)
+			return
+		}
+		_, _ = node.active.Pop()
+		*skipped = append(*skipped, child)
+	}
+	return
+}
+
+// OnComplete implements [CompletionObserver].
+func (q *hierarchicalFairTaskQueue) OnComplete(t *Task) {
+	q.inFlight.completed(t)
+}
@@ -0,0 +1,121 @@
+package sim
+
+import (
+	"slices"
+	"testing"
+)
+
+func Test_FairnessPathParents(t *testing.T) {
+	testCases := map[string][]string{
+		"high":                   nil,
+		"acct1/merchantA":        {"acct1"},
+		"acct1/merchantA/export": {"acct1", "acct1/merchantA"},
+	}
+	for key, expected := range testCases {
+		if actual := FairnessPathParents(key); !slices.Equal(actual, expected) {
+			t.Errorf("expect the parents of %q to be %q, was %q", key, expected, actual)
+			t.Fail()
+		}
+	}
+}
+
+func Test_HierarchicalFairTaskQueue(t *testing.T) {
+	rq := NewHierarchicalFairTaskQueue(CostModelTaskCount, nil)
+
+	// acct1 has four fairness keys to acct2's one, but the accounts have the same weight.
+	for x := 0; x < 8; x++ {
+		rq.Push(Task{ID: NewUUID(), FairnessKey: "acct1/merchantA/export"})
+		rq.Push(Task{ID: NewUUID(), FairnessKey: "acct1/merchantA/sync"})
+		rq.Push(Task{ID: NewUUID(), FairnessKey: "acct1/merchantB/export"})
+		rq.Push(Task{ID: NewUUID(), FairnessKey: "acct1/merchantC/export"})
+		rq.Push(Task{ID: NewUUID(), FairnessKey: "acct2/merchantA/export"})
+	}
+	if rq.Len() != 40 {
+		t.Errorf("expect tq length to be 40, was %d", rq.Len())
+		t.Fail()
+	}
+
+	counts := make(map[string]int)
+	for x := 0; x < 12; x++ {
+		task, ok := rq.Pull()
+		if !ok {
+			t.Errorf("expect pull to be ok")
+			t.FailNow()
+		}
+		counts[task.FairnessKey]++
+	}
+	if counts["acct2/merchantA/export"] != 6 {
+		t.Errorf("expect acct2 to be served half of the pulls, was %d of 12", counts["acct2/merchantA/export"])
+		t.Fail()
+	}
+	if counts["acct1/merchantB/export"] != 2 || counts["acct1/merchantC/export"] != 2 {
+		t.Errorf("expect each merchant of acct1 to be served 2 pulls, was %v", counts)
+		t.Fail()
+	}
+	if counts["acct1/merchantA/export"] != 1 || counts["acct1/merchantA/sync"] != 1 {
+		t.Errorf("expect each fairness key of acct1/merchantA to be served 1 pull, was %v", counts)
+		t.Fail()
+	}
+
+	for rq.Len() > 0 {
+		if _, ok := rq.Pull(); !ok {
+			t.Errorf("expect pull to be ok while tq length is %d", rq.Len())
+			t.FailNow()
+		}
+	}
+	if _, ok := rq.Pull(); ok {
+		t.Errorf("expect pull from an empty tq not to be ok")
+		t.Fail()
+	}
+}
+
+func Test_HierarchicalFairTaskQueue_weights(t *testing.T) {
+	rq := NewHierarchicalFairTaskQueue(CostModelTaskCount, map[string]float64{"acct1": 3})
+
+	for x := 0; x < 10; x++ {
+		rq.Push(Task{ID: NewUUID(), FairnessKey: "acct1/merchantA", Fairness: 1})
+		rq.Push(Task{ID: NewUUID(), FairnessKey: "acct1/merchantB", Fairness: 2})
+		rq.Push(Task{ID: NewUUID(), FairnessKey: "acct2", Fairness: 1})
+	}
+
+	// acct1 is served 3 pulls for each of acct2's, split 1:2 between its merchants by fairness weight.
+	counts := make(map[string]int)
+	for x := 0; x < 12; x++ {
+		task, ok := rq.Pull()
+		if !ok {
+			t.Errorf("expect pull to be ok")
+			t.FailNow()
+		}
+		counts[task.FairnessKey]++
+	}
+	if counts["acct2"] != 3 || counts["acct1/merchantA"] != 3 || counts["acct1/merchantB"] != 6 {
+		t.Errorf("expect acct2, acct1/merchantA and acct1/merchantB to be served 3, 3 and 6 pulls, was %v", counts)
+		t.Fail()
+	}
+}
+
+func Test_HierarchicalFairTaskQueue_idle(t *testing.T) {
+	rq := NewHierarchicalFairTaskQueue(CostModelTaskCount, nil)
+
+	for x := 0; x < 4; x++ {
+		rq.Push(Task{ID: NewUUID(), FairnessKey: "acct1/merchantA"})
+	}
+	for x := 0; x < 4; x++ {
+		_, _ = rq.Pull()
+	}
+
+	// acct2 was idle while acct1 was served, so it doesn't get to catch up.
+	for x := 0; x < 4; x++ {
+		rq.Push(Task{ID: NewUUID(), FairnessKey: "acct1/merchantA"})
+		rq.Push(Task{ID: NewUUID(), FairnessKey: "acct2/merchantA"})
+	}
+	counts := make(map[string]int)
+	for x := 0; x < 4; x++ {
+		task, _ := rq.Pull()
+		counts[task.FairnessKey]++
+	}
+	if counts["acct1/merchantA"] != 2 || counts["acct2/merchantA"] != 2 {
+		t.Errorf("expect idle paths not to bank credit, was %v", counts)
+		t.Fail()
+	}
+}
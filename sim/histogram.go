@@ -0,0 +1,152 @@
+package sim
+
+import (
+	"math"
+	"math/bits"
+	"time"
+)
+
+// histogramSubBucketBits is the number of bits of a duration kept exactly by a [Histogram],
+// i.e. each power of two range of durations is split into 2^histogramSubBucketBits buckets.
+const histogramSubBucketBits = 7
+
+// HistogramRelativeError is the largest relative error of a percentile of a [Histogram].
+const HistogramRelativeError = 1.0 / (2 << histogramSubBucketBits)
+
+// Histogram is a streaming log-linear histogram of durations, in the style of an HDR histogram,
+// that answers percentiles within [HistogramRelativeError] of the recorded durations.
+//
+// Durations under 2^(histogramSubBucketBits+1) nanoseconds are counted exactly, and every power of two
+// range above that is split into the same number of linear buckets, such that the memory used
+// depends on the range of the durations recorded rather than how many there were. The count,
+// average, min and max are exact.
+//
+// The zero value is an empty histogram ready to use.
+type Histogram struct {
+	// counts are the counts of the buckets from index offset.
+	counts []uint64
+	offset int
+
+	count            uint64
+	sumHi, sumLo     uint64
+	minimum, maximum time.Duration
+}
+
+// histogramIndex returns the index of the bucket a (non-negative) duration is counted in.
+func histogramIndex(d time.Duration) int {
+	shift := bits.Len64(uint64(d)) - histogramSubBucketBits - 1
+	if shift < 0 {
+		return int(d)
+	}
+	return (shift+1)<<histogramSubBucketBits + int(uint64(d)>>shift) - 1<<histogramSubBucketBits
+}
+
+// histogramBucket returns the range of durations counted in a bucket, from lower inclusive to upper exclusive.
+func histogramBucket(index int) (lower, upper time.Duration) {
+	shift := index>>histogramSubBucketBits - 1
+	if shift < 0 {
+		return time.Duration(index), time.Duration(index + 1)
+	}
+	mantissa := index&(1<<histogramSubBucketBits-1) + 1<<histogramSubBucketBits
+	return time.Duration(mantissa) << shift, time.Duration(mantissa+1) << shift
+}
+
+// Record adds a duration to the histogram, where negative durations are recorded as 0.
+func (h *Histogram) Record(d time.Duration) {
+	d = max(d, 0)
+	index := histogramIndex(d)
+	h.grow(index)
+	h.counts[index-h.offset]++
+	if h.count == 0 || d < h.minimum {
+		h.minimum = d
+	}
+	h.maximum = max(h.maximum, d)
+	h.count++
+	h.sumLo, h.sumHi = addUint128(h.sumLo, h.sumHi, uint64(d), 0)
+}
+
+// Merge adds the durations recorded by another histogram to this one.
+func (h *Histogram) Merge(other *Histogram) {
+	if other.count == 0 {
+		return
+	}
+	h.grow(other.offset)
+	h.grow(other.offset + len(other.counts) - 1)
+	for index, count := range other.counts {
+		h.counts[index+other.offset-h.offset] += count
+	}
+	if h.count == 0 || other.minimum < h.minimum {
+		h.minimum = other.minimum
+	}
+	h.maximum = max(h.maximum, other.maximum)
+	h.count += other.count
+	h.sumLo, h.sumHi = addUint128(h.sumLo, h.sumHi, other.sumLo, other.sumHi)
+}
+
+// grow extends the buckets to include a bucket index.
+func (h *Histogram) grow(index int) {
+	switch {
+	case len(h.counts) == 0:
+		h.counts = make([]uint64, 1, 1<<histogramSubBucketBits)
+		h.offset = index
+	case index < h.offset:
+		grown := make([]uint64, len(h.counts)+h.offset-index, cap(h.counts)+h.offset-index)
+		copy(grown[h.offset-index:], h.counts)
+		h.counts, h.offset = grown, index
+	case index >= h.offset+len(h.counts):
+		h.counts = append(h.counts, make([]uint64, index-h.offset-len(h.counts)+1)...)
+	}
+}
+
+// addUint128 adds two 128 bit integers, such that the sum of the durations can't overflow.
+func addUint128(aLo, aHi, bLo, bHi uint64) (lo, hi uint64) {
+	var carry uint64
+	lo, carry = bits.Add64(aLo, bLo, 0)
+	hi = aHi + bHi + carry
+	return
+}
+
+// Count returns the number of durations recorded.
+func (h *Histogram) Count() int {
+	return int(h.count)
+}
+
+// Avg returns the average of the durations recorded.
+func (h *Histogram) Avg() time.Duration {
+	if h.count == 0 {
+		return 0
+	}
+	avg, _ := bits.Div64(h.sumHi, h.sumLo, h.count)
+	return time.Duration(avg)
+}
+
+// Min returns the shortest duration recorded.
+func (h *Histogram) Min() time.Duration {
+	return h.minimum
+}
+
+// Max returns the longest duration recorded.
+func (h *Histogram) Max() time.Duration {
+	return h.maximum
+}
+
+// Percentile returns the duration at a percentile from 0 to 100, i.e. the shortest
+// duration that at least that percent of the durations recorded are at most.
+func (h *Histogram) Percentile(percentile float64) time.Duration {
+	if h.count == 0 {
+		return 0
+	}
+	rank := max(uint64(math.Ceil(percentile/100*float64(h.count))), 1)
+	if rank >= h.count {
+		return h.maximum
+	}
+	var seen uint64
+	for index, count := range h.counts {
+		if seen += count; seen >= rank {
+			lower, upper := histogramBucket(index + h.offset)
+			// the middle of the bucket is within the relative error of any duration in it.
+			return min(max(lower+(upper-1-lower)/2, h.minimum), h.maximum)
+		}
+	}
+	return h.maximum
+}
@@ -0,0 +1,78 @@
+package sim
+
+import (
+	"math"
+	"math/rand/v2"
+	"slices"
+	"testing"
+	"time"
+)
+
+func Test_Histogram(t *testing.T) {
+	r := rand.New(rand.NewPCG(1, 2))
+	var h, first, second Histogram
+	var samples []time.Duration
+	for index := 0; index < 100_000; index++ {
+		// spread the samples from nanoseconds to hours.
+		d := time.Duration(math.Exp(r.Float64() * math.Log(float64(4*time.Hour))))
+		samples = append(samples, d)
+		h.Record(d)
+		if index%2 == 0 {
+			first.Record(d)
+		} else {
+			second.Record(d)
+		}
+	}
+	slices.Sort(samples)
+
+	if h.Count() != len(samples) || h.Min() != samples[0] || h.Max() != samples[len(samples)-1] {
+		t.Errorf("expect the exact count, min and max, was %d, %v and %v", h.Count(), h.Min(), h.Max())
+		t.Fail()
+	}
+	if avg := AvgDurations(samples); h.Avg() != avg {
+		t.Errorf("expect the exact average %v, was %v", avg, h.Avg())
+		t.Fail()
+	}
+	for _, percentile := range []float64{0, 1, 25, 50, 90, 95, 99, 99.9, 99.99, 100} {
+		expected := samples[max(int(math.Ceil(percentile/100*float64(len(samples))))-1, 0)]
+		actual := h.Percentile(percentile)
+		if relativeError := math.Abs(float64(actual-expected)) / float64(expected); relativeError > HistogramRelativeError {
+			t.Errorf("expect %s within %v of %v, was %v", FormatPercentile(percentile), HistogramRelativeError, expected, actual)
+			t.Fail()
+		}
+	}
+
+	first.Merge(&second)
+	if first.Count() != h.Count() || first.Avg() != h.Avg() || first.Min() != h.Min() || first.Max() != h.Max() {
+		t.Errorf("expect merged histograms to have the same count, average, min and max as one histogram")
+		t.Fail()
+	}
+	for _, percentile := range []float64{1, 50, 99, 99.9} {
+		if first.Percentile(percentile) != h.Percentile(percentile) {
+			t.Errorf("expect merged histograms to have the same %s as one histogram, was %v vs. %v", FormatPercentile(percentile), first.Percentile(percentile), h.Percentile(percentile))
+			t.Fail()
+		}
+	}
+}
+
+func Test_Histogram_exact(t *testing.T) {
+	var h Histogram
+	for _, d := range []time.Duration{3, 1, 2, 4, -1} {
+		h.Record(d)
+	}
+	if h.Percentile(50) != 2 || h.Percentile(100) != 4 || h.Min() != 0 {
+		t.Errorf("expect short durations to be counted exactly, was p50 %v, p100 %v and min %v", h.Percentile(50), h.Percentile(100), h.Min())
+		t.Fail()
+	}
+
+	var empty Histogram
+	if empty.Percentile(99) != 0 || empty.Avg() != 0 {
+		t.Errorf("expect an empty histogram to have zero percentiles")
+		t.Fail()
+	}
+	h.Merge(&empty)
+	if h.Count() != 5 {
+		t.Errorf("expect merging an empty histogram to change nothing, was %d", h.Count())
+		t.Fail()
+	}
+}
@@ -0,0 +1,29 @@
+package sim
+
+// InFlightCounter is an optional interface for task queues that track how many
+// of the tasks they dispatched have not completed yet, i.e. are held by workers.
+//
+// Task queues can only track this if they also implement [CompletionObserver].
+type InFlightCounter interface {
+	InFlight(fairnessKey string) int
+}
+
+// inFlight counts dispatched but not yet completed tasks by fairness key.
+type inFlight map[string]int
+
+func (f inFlight) dispatched(t *Task) {
+	f[t.FairnessKey]++
+}
+
+func (f inFlight) completed(t *Task) {
+	if f[t.FairnessKey] <= 1 {
+		delete(f, t.FairnessKey)
+		return
+	}
+	f[t.FairnessKey]--
+}
+
+// InFlight implements [InFlightCounter].
+func (f inFlight) InFlight(fairnessKey string) int {
+	return f[fairnessKey]
+}
@@ -0,0 +1,53 @@
+package sim
+
+import (
+	"math/rand/v2"
+	"testing"
+	"time"
+)
+
+func Test_InFlightCounter(t *testing.T) {
+	r := rand.NewPCG(123, 123)
+	queues := map[string]TaskQueue{
+		"fairness": NewPriorityFairnessTaskQueue(rand.New(r), nil, CostModelTaskCount, AgingPolicy{}, nil),
+		"feeder":   NewFeederTaskQueue(rand.New(r), NewSimulatedClock(time.Now()), CostModelTaskCount, nil),
+		"drr":      NewDeficitRoundRobinTaskQueue(CostModelTaskCount),
+		"wfq":      NewWeightedFairTaskQueue(CostModelTaskCount),
+		"edf":      NewEarliestDeadlineTaskQueue(rand.New(r)),
+		"hfq":      NewHierarchicalFairTaskQueue(CostModelTaskCount, nil),
+	}
+	for name, rq := range queues {
+		rq.Push(Task{ID: NewUUID(), FairnessKey: "high", Fairness: 70})
+		rq.Push(Task{ID: NewUUID(), FairnessKey: "high", Fairness: 70})
+		rq.Push(Task{ID: NewUUID(), FairnessKey: "low", Fairness: 10})
+
+		var pulled []*Task
+		for x := 0; x < 3; x++ {
+			task, ok := rq.Pull()
+			if !ok {
+				t.Errorf("%s: expect pull to be ok", name)
+				t.FailNow()
+			}
+			pulled = append(pulled, task)
+		}
+
+		counter, ok := rq.(InFlightCounter)
+		if !ok {
+			t.Errorf("%s: expect queue to implement InFlightCounter", name)
+			t.FailNow()
+		}
+		if counter.InFlight("high") != 2 || counter.InFlight("low") != 1 {
+			t.Errorf("%s: expect in flight high/low to be 2/1, was %d/%d", name, counter.InFlight("high"), counter.InFlight("low"))
+			t.Fail()
+		}
+
+		observer := rq.(CompletionObserver)
+		for _, task := range pulled {
+			observer.OnComplete(task)
+		}
+		if counter.InFlight("high") != 0 || counter.InFlight("low") != 0 {
+			t.Errorf("%s: expect in flight high/low to be 0/0, was %d/%d", name, counter.InFlight("high"), counter.InFlight("low"))
+			t.Fail()
+		}
+	}
+}
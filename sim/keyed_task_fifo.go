@@ -0,0 +1,93 @@
+package sim
+
+// newKeyedTaskFifo returns a new fifo of tasks that keeps a lane per fairness key, such that
+// the oldest task of the fairness keys a filter allows is found without visiting the queued
+// tasks of the other fairness keys.
+func newKeyedTaskFifo() *keyedTaskFifo {
+	return &keyedTaskFifo{
+		lanes: make(map[string]*Queue[keyedTask]),
+		heads: NewHeap(keyedTaskLess),
+	}
+}
+
+type keyedTaskFifo struct {
+	len   int
+	seq   uint64
+	lanes map[string]*Queue[keyedTask]
+	// heads are the tasks at the head of each lane, oldest first.
+	heads *Heap[keyedTask]
+}
+
+type keyedTask struct {
+	task *Task
+	seq  uint64
+}
+
+func keyedTaskLess(i, j keyedTask) bool {
+	return i.seq < j.seq
+}
+
+func (f *keyedTaskFifo) Len() int {
+	return f.len
+}
+
+func (f *keyedTaskFifo) Push(t *Task) {
+	f.seq++
+	item := keyedTask{task: t, seq: f.seq}
+	lane, ok := f.lanes[t.FairnessKey]
+	if !ok {
+		lane = &Queue[keyedTask]{}
+		f.lanes[t.FairnessKey] = lane
+		f.heads.Push(item)
+	}
+	lane.Push(item)
+	f.len++
+}
+
+// Peek returns the oldest task of the fairness keys allow returns true for.
+func (f *keyedTaskFifo) Peek(allow func(fairnessKey string) bool) (task *Task, ok bool) {
+	skipped := f.skip(allow)
+	if head, hasHead := f.heads.Peek(); hasHead {
+		task, ok = head.task, true
+	}
+	f.unskip(skipped)
+	return
+}
+
+// Pop removes and returns the oldest task of the fairness keys allow returns true for.
+func (f *keyedTaskFifo) Pop(allow func(fairnessKey string) bool) (task *Task, ok bool) {
+	skipped := f.skip(allow)
+	if head, hasHead := f.heads.Pop(); hasHead {
+		task, ok = head.task, true
+		lane := f.lanes[task.FairnessKey]
+		_, _ = lane.Pop()
+		if next, hasNext := lane.Peek(); hasNext {
+			f.heads.Push(next)
+		} else {
+			delete(f.lanes, task.FairnessKey)
+		}
+		f.len--
+	}
+	f.unskip(skipped)
+	return
+}
+
+// skip pops the heads of the lanes that allow returns false for until the oldest head is of an
+// allowed fairness key, returning the heads popped such that they can be restored with unskip.
+func (f *keyedTaskFifo) skip(allow func(fairnessKey string) bool) (skipped []keyedTask) {
+	for f.heads.Len() > 0 {
+		head, _ := f.heads.Peek()
+		if allow(head.task.FairnessKey) {
+			return
+		}
+		_, _ = f.heads.Pop()
+		skipped = append(skipped, head)
+	}
+	return
+}
+
+func (f *keyedTaskFifo) unskip(skipped []keyedTask) {
+	for _, head := range skipped {
+		f.heads.Push(head)
+	}
+}
@@ -0,0 +1,109 @@
+package sim
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Latencies are the latency distributions of a set of tasks.
+type Latencies struct {
+	// Queued is the time from a task being created until it was dispatched to a worker,
+	// where tasks still queued at the end of the simulation are queued until the end.
+	Queued LatencyDistribution
+	// Service is the time from a task being dispatched until it completed.
+	Service LatencyDistribution
+	// EndToEnd is the time from a task being created until it completed.
+	EndToEnd LatencyDistribution
+}
+
+// LatencyDistribution summarizes a distribution of latencies, where the count, average and max
+// are exact and the percentiles are within [HistogramRelativeError] of the exact latencies.
+type LatencyDistribution struct {
+	Count       int
+	Avg         time.Duration
+	Max         time.Duration
+	Percentiles []LatencyPercentile
+}
+
+// LatencyPercentile is the latency at a percentile, e.g. 99.9.
+type LatencyPercentile struct {
+	Percentile float64
+	Value      time.Duration
+}
+
+// Percentile returns the latency at a given percentile, if it was computed.
+func (ld LatencyDistribution) Percentile(percentile float64) (time.Duration, bool) {
+	for _, p := range ld.Percentiles {
+		if p.Percentile == percentile {
+			return p.Value, true
+		}
+	}
+	return 0, false
+}
+
+// FormatPercentile formats a percentile as e.g. "p99" or "p99.9".
+func FormatPercentile(percentile float64) string {
+	return "p" + strconv.FormatFloat(percentile, 'f', -1, 64)
+}
+
+// ParsePercentiles parses a comma separated list of percentiles, e.g. "50,99,99.9".
+func ParsePercentiles(value string) (output []float64, err error) {
+	for _, field := range strings.Split(value, ",") {
+		var percentile float64
+		percentile, err = strconv.ParseFloat(strings.TrimPrefix(strings.TrimSpace(field), "p"), 64)
+		if err != nil || percentile <= 0 || percentile > 100 {
+			return nil, fmt.Errorf("invalid percentile %q: must be greater than 0 and at most 100", field)
+		}
+		output = append(output, percentile)
+	}
+	return
+}
+
+func newLatencyDistribution(h *Histogram, percentiles []float64) LatencyDistribution {
+	if h.Count() == 0 {
+		return LatencyDistribution{}
+	}
+	ld := LatencyDistribution{
+		Count:       h.Count(),
+		Avg:         h.Avg(),
+		Max:         h.Max(),
+		Percentiles: make([]LatencyPercentile, len(percentiles)),
+	}
+	for index, percentile := range percentiles {
+		ld.Percentiles[index] = LatencyPercentile{Percentile: percentile, Value: h.Percentile(percentile)}
+	}
+	return ld
+}
+
+// latencyHistograms are the latencies of a set of tasks.
+type latencyHistograms struct {
+	queued   Histogram
+	service  Histogram
+	endToEnd Histogram
+}
+
+func (lh *latencyHistograms) completed(t *Task) {
+	lh.queued.Record(t.DispatchedUTC.Sub(t.CreatedUTC))
+	lh.service.Record(t.CompletedUTC.Sub(t.DispatchedUTC))
+	lh.endToEnd.Record(t.CompletedUTC.Sub(t.CreatedUTC))
+}
+
+func (lh *latencyHistograms) stillQueued(t *Task, finalTimestamp time.Time) {
+	lh.queued.Record(finalTimestamp.Sub(t.CreatedUTC))
+}
+
+func (lh *latencyHistograms) merge(other *latencyHistograms) {
+	lh.queued.Merge(&other.queued)
+	lh.service.Merge(&other.service)
+	lh.endToEnd.Merge(&other.endToEnd)
+}
+
+// latencies returns the latency distributions at the percentiles.
+func (lh *latencyHistograms) latencies(percentiles []float64) (output Latencies) {
+	output.Queued = newLatencyDistribution(&lh.queued, percentiles)
+	output.Service = newLatencyDistribution(&lh.service, percentiles)
+	output.EndToEnd = newLatencyDistribution(&lh.endToEnd, percentiles)
+	return
+}
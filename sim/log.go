@@ -2,9 +2,14 @@ package sim
 
 import (
 	"fmt"
+	"io"
+	"os"
 	"strings"
 )
 
+// LogOutput is where the simulation logs its progress.
+var LogOutput io.Writer = os.Stdout
+
 type logTag struct {
 	K string
 	V any
@@ -15,5 +20,5 @@ func log(message string, tags ...logTag) {
 	for _, t := range tags {
 		tagStrings = append(tagStrings, fmt.Sprintf("%s=%v", t.K, t.V))
 	}
-	fmt.Println(message, strings.Join(tagStrings, " "))
+	fmt.Fprintln(LogOutput, message, strings.Join(tagStrings, " "))
 }
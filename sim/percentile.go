@@ -1,53 +1,20 @@
 package sim
 
-import (
-	"cmp"
-	"math"
-	"sort"
-	"time"
-)
-
-func p95(durations []time.Duration) time.Duration {
-	return percentile(durations, 95.0)
-}
+import "time"
 
 type Operatable interface {
 	time.Duration | ~float64 | ~int
 }
 
-func percentile[T Operatable](input []T, percent float64) (output T) {
-	if len(input) == 0 {
-		return
-	}
-	output = percentileSorted(copySort(input), percent)
-	return
-}
-
-func percentileSorted[T Operatable](sortedInput []T, percent float64) (percentile T) {
-	if len(sortedInput) == 0 {
-		return
+// quantiles returns the durations at each whole percentile from 0 to 100 of a histogram.
+func quantiles(h *Histogram) []time.Duration {
+	if h.Count() == 0 {
+		return nil
 	}
-	index := (percent / 100.0) * float64(len(sortedInput))
-	i := int(math.RoundToEven(index))
-	if index == float64(int64(index)) {
-		percentile = (sortedInput[i-1] + sortedInput[i]) / 2.0
-	} else {
-		percentile = sortedInput[i-1]
+	output := make([]time.Duration, 101)
+	output[0] = h.Min()
+	for percent := 1; percent <= 100; percent++ {
+		output[percent] = h.Percentile(float64(percent))
 	}
-	return percentile
-}
-
-// copySort copies and sorts a slice ascending.
-func copySort[T cmp.Ordered](input []T) []T {
-	copy := copySlice(input)
-	sort.Slice(copy, func(i, j int) bool {
-		return copy[i] < copy[j]
-	})
-	return copy
-}
-
-func copySlice[T any](input []T) []T {
-	output := make([]T, len(input))
-	copy(output, input)
 	return output
 }
@@ -0,0 +1,198 @@
+package sim
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"slices"
+	"time"
+)
+
+// Phase changes the workload, capacity or task queue limits of the simulation
+// from an offset into the simulation until the start of the next phase.
+//
+// Each phase is applied on top of the simulation config (rather than on top of
+// the previous phase), i.e. anything a phase does not set reverts to the
+// simulation config when the phase starts.
+type Phase struct {
+	Name string
+	// Start is the offset from the start of the simulation at which the phase begins.
+	Start time.Duration
+
+	// ArrivalProcess replaces the simulation's arrival process.
+	ArrivalProcess ArrivalProcess
+	// Tenants replaces the simulation's tenants.
+	Tenants []TenantProfile
+	// ArrivalMultipliers scales the arrival rate of tenants by fairness key, e.g. a multiplier
+	// of 10 has a tenant submit ten times as many tasks as it otherwise would.
+	ArrivalMultipliers map[string]float64
+
+	// WorkerCount changes the number of workers; removed workers finish
+	// the tasks they hold but are not given new tasks.
+	WorkerCount int
+
+	// ConcurrencyLimit changes the default per fairness key concurrency limit, where
+	// a negative limit removes the limit.
+	ConcurrencyLimit int
+	// ConcurrencyLimits overrides the concurrency limit for specific fairness keys.
+	ConcurrencyLimits map[string]int
+	// RateLimits overrides the rate limits of the "feeder" task queue for specific fairness keys.
+	RateLimits map[string]Limit
+}
+
+func (p Phase) changesConcurrencyLimits() bool {
+	return p.ConcurrencyLimit != 0 || len(p.ConcurrencyLimits) > 0
+}
+
+// PhasesOrDefault returns the phases ordered by start, preceded by a "baseline" phase
+// if the first phase starts after the start of the simulation.
+func (sc SimulationConfig) PhasesOrDefault() []Phase {
+	if len(sc.Phases) == 0 {
+		return nil
+	}
+	output := slices.Clone(sc.Phases)
+	slices.SortStableFunc(output, func(i, j Phase) int {
+		return cmp.Compare(i.Start, j.Start)
+	})
+	if output[0].Start > 0 {
+		output = append([]Phase{{Name: "baseline"}}, output...)
+	}
+	return output
+}
+
+// phaseEnd returns the offset at which the phase with the given index ends.
+func (s *Simulation) phaseEnd(index int) time.Duration {
+	if index+1 < len(s.phases) {
+		return s.phases[index+1].Start
+	}
+	return s.Config.DurationOrDefault()
+}
+
+// phaseAt returns the index of the phase a given offset falls in.
+func (s *Simulation) phaseAt(offset time.Duration) int {
+	index, found := slices.BinarySearchFunc(s.phases, offset, func(p Phase, offset time.Duration) int {
+		return cmp.Compare(p.Start, offset)
+	})
+	if found {
+		return index
+	}
+	return max(index-1, 0)
+}
+
+func (s *Simulation) initPhases() error {
+	s.phases = s.Config.PhasesOrDefault()
+	s.phase = -1
+	s.phaseQueueLengths = nil
+	if slices.ContainsFunc(s.phases, Phase.changesConcurrencyLimits) {
+		if _, ok := FindTaskQueue[ConcurrencyLimiter](s.TaskQueue); !ok {
+			inner, ok := s.TaskQueue.(FilteredTaskQueue)
+			if !ok {
+				return fmt.Errorf("phases change concurrency limits, which task queue %T does not support", s.TaskQueue)
+			}
+			s.TaskQueue = NewConcurrencyLimitedTaskQueue(inner, 0, nil)
+		}
+	}
+	if limiter, ok := FindTaskQueue[ConcurrencyLimiter](s.TaskQueue); ok {
+		s.baseConcurrencyLimit, s.baseConcurrencyLimits = limiter.ConcurrencyLimits()
+	}
+	if setter, ok := FindTaskQueue[RateLimitSetter](s.TaskQueue); ok {
+		s.baseRateLimits = setter.RateLimits()
+	}
+	return nil
+}
+
+// advancePhases enters each phase that has started by a given time,
+// returning if any phase was entered.
+func (s *Simulation) advancePhases(now time.Time) (entered bool) {
+	for s.phase+1 < len(s.phases) && now.Sub(s.startTime) >= s.phases[s.phase+1].Start {
+		s.enterPhase(now, s.phase+1)
+		entered = true
+	}
+	return
+}
+
+func (s *Simulation) enterPhase(now time.Time, index int) {
+	if s.phase >= 0 {
+		s.phaseQueueLengths = append(s.phaseQueueLengths, s.TaskQueue.Len())
+	}
+	s.phase = index
+	phase := s.phases[index]
+
+	s.arrivals = s.Config.ArrivalProcessOrDefault()
+	if phase.ArrivalProcess != nil {
+		s.arrivals = phase.ArrivalProcess
+	}
+	if len(phase.Tenants) > 0 {
+		s.setTenants(phase.Tenants)
+	} else {
+		s.setTenants(s.Config.TenantsOrDefault())
+	}
+	s.arrivalMultipliers = phase.ArrivalMultipliers
+	if phase.WorkerCount > 0 {
+		s.setWorkerCount(phase.WorkerCount)
+	} else {
+		s.setWorkerCount(s.Config.WorkerCountOrDefault())
+	}
+	if limiter, ok := FindTaskQueue[ConcurrencyLimiter](s.TaskQueue); ok {
+		defaultLimit := s.baseConcurrencyLimit
+		if phase.ConcurrencyLimit != 0 {
+			defaultLimit = phase.ConcurrencyLimit
+		}
+		limiter.SetConcurrencyLimits(defaultLimit, mergeLimits(s.baseConcurrencyLimits, phase.ConcurrencyLimits))
+	}
+	if setter, ok := FindTaskQueue[RateLimitSetter](s.TaskQueue); ok {
+		setter.SetRateLimits(mergeLimits(s.baseRateLimits, phase.RateLimits))
+	}
+
+	log(
+		"entering phase",
+		logTag{"name", phase.Name},
+		logTag{"ts", now.Format("15:04")},
+		logTag{"elapsed", now.Sub(s.startTime)},
+		logTag{"tql", s.TaskQueue.Len()},
+	)
+}
+
+// setWorkerCount adds workers, or retires the workers past the given count
+// such that they are not given new tasks.
+func (s *Simulation) setWorkerCount(count int) {
+	slots := s.Config.WorkerTaskSlotsOrDefault()
+	for x := 0; x < max(count, len(s.Workers)); x++ {
+		w, ok := s.Workers[x]
+		if !ok {
+			w = &Worker{ID: x, Tasks: make(TaskLookup, slots)}
+			s.Workers.Add(w)
+		}
+		if x < count {
+			w.MaxTasks = slots
+		} else {
+			w.MaxTasks = 0
+		}
+	}
+}
+
+// scaleArrivals applies the current phase's arrival multiplier for a tenant to a number of arrivals,
+// rounding randomly such that the expected number of arrivals is scaled exactly.
+func (s *Simulation) scaleArrivals(tenant *TenantProfile, count int) int {
+	multiplier, ok := s.arrivalMultipliers[tenant.FairnessKey]
+	if !ok {
+		return count
+	}
+	scaled := float64(count) * max(multiplier, 0)
+	whole := math.Floor(scaled)
+	if fraction := scaled - whole; fraction > 0 && s.r.Float64() < fraction {
+		whole++
+	}
+	return int(whole)
+}
+
+func mergeLimits[V any](base, overrides map[string]V) map[string]V {
+	output := make(map[string]V, len(base)+len(overrides))
+	for key, value := range base {
+		output[key] = value
+	}
+	for key, value := range overrides {
+		output[key] = value
+	}
+	return output
+}
@@ -1,7 +1,19 @@
 package sim
 
+import "fmt"
+
 type Priority int
 
+// ParsePriority parses a priority from its string form, e.g. "P2".
+func ParsePriority(value string) (Priority, error) {
+	for _, p := range []Priority{P0, P1, P2, P3, P4} {
+		if p.String() == value {
+			return p, nil
+		}
+	}
+	return DefaultPriority, fmt.Errorf("invalid priority: %q", value)
+}
+
 func (p Priority) String() string {
 	switch p {
 	case P0:
@@ -27,6 +39,9 @@ const (
 	P4
 )
 
+// priorities are the priorities from highest to lowest.
+var priorities = []Priority{P0, P1, P2, P3, P4}
+
 const (
 	DefaultPriority = P2
 )
@@ -1,19 +1,53 @@
 package sim
 
-import "math/rand/v2"
+import (
+	"math/rand/v2"
+	"slices"
+	"time"
+)
 
-func NewPriorityFairnessTaskQueue(r *rand.Rand) TaskQueue {
+// NewPriorityFairnessTaskQueue returns a new priority fairness task queue.
+//
+// Tasks are served strictly by priority, and within a priority a fairness key is
+// chosen at random in proportion to its fairness weight. The aging policy (if any)
+// raises the priority of tasks as they wait, where each fairness key competes at
+// the priority of its oldest task that was raised the most.
+//
+// With class weights, priorities instead share dispatches in proportion to their weights,
+// i.e. the priority to serve is chosen at random by weight among those with tasks queued,
+// such that the share of an empty priority goes to the others. Priorities without a
+// class weight have a weight of 1, and priorities with a weight of 0 are only served
+// when no priority with a (positive) weight has tasks queued.
+//
+// With the [CostModelWorkerTime] cost model each key's weight is divided by the
+// average worker-seconds its tasks have cost so far, such that keys receive worker
+// time (rather than task counts) in proportion to their fairness weights.
+func NewPriorityFairnessTaskQueue(r *rand.Rand, c Clock, costModel CostModel, aging AgingPolicy, classWeights map[Priority]int) TaskQueue {
 	return &priorityFairnessTaskQueue{
 		fairnessKeyWeights: make(map[string]float64),
+		clock:              c,
+		aging:              aging,
+		classWeights:       classWeights,
+		costModel:          costModel,
+		costs:              make(map[string]*runningCost),
+		inFlight:           make(inFlight),
 		r:                  r,
 	}
 }
 
 type priorityFairnessTaskQueue struct {
 	len                int
-	storage            [5]map[string]map[UUID]*Task
+	storage            [5]map[string]*Queue[*Task]
+	keys               []string // the fairness keys with tasks queued (at any priority), sorted
 	fairnessKeyWeights map[string]float64
-	r                  *rand.Rand
+	clock              Clock
+	aging              AgingPolicy
+	classWeights       map[Priority]int
+	costModel          CostModel
+	costs              map[string]*runningCost
+	totalCost          runningCost
+	inFlight
+	r *rand.Rand
 }
 
 func (q *priorityFairnessTaskQueue) Len() int {
@@ -22,36 +56,206 @@ func (q *priorityFairnessTaskQueue) Len() int {
 
 func (q *priorityFairnessTaskQueue) Push(t Task) {
 	if q.storage[t.Priority] == nil {
-		q.storage[t.Priority] = make(map[string]map[UUID]*Task)
+		q.storage[t.Priority] = make(map[string]*Queue[*Task])
 	}
 	if q.storage[t.Priority][t.FairnessKey] == nil {
-		q.storage[t.Priority][t.FairnessKey] = make(map[UUID]*Task)
+		q.storage[t.Priority][t.FairnessKey] = &Queue[*Task]{}
+		if index, found := slices.BinarySearch(q.keys, t.FairnessKey); !found {
+			q.keys = slices.Insert(q.keys, index, t.FairnessKey)
+		}
 	}
-	q.storage[t.Priority][t.FairnessKey][t.ID] = &t
+	q.storage[t.Priority][t.FairnessKey].Push(&t)
 	q.fairnessKeyWeights[t.FairnessKey] = t.Fairness
 	q.len++
 }
 
 func (q *priorityFairnessTaskQueue) Pull() (task *Task, ok bool) {
+	return q.PullFiltered(allowAllFairnessKeys)
+}
+
+// PullFiltered implements [FilteredTaskQueue].
+func (q *priorityFairnessTaskQueue) PullFiltered(allow func(fairnessKey string) bool) (task *Task, ok bool) {
+	level, candidates := q.candidates(allow)
+	if len(candidates) == 0 {
+		return
+	}
+	fairnessKey := RandomKeyByWeight(q.r, q.keys, q.effectiveWeights(filterMapBySharedKeys(q.fairnessKeyWeights, candidates)))
+	p := candidates[fairnessKey]
+	task, ok = q.storage[p][fairnessKey].Pop()
+	if !ok {
+		return
+	}
+	if q.storage[p][fairnessKey].Len() == 0 {
+		q.deleteLane(p, fairnessKey)
+	}
+	task.Boost = int(task.Priority - level)
+	q.len--
+	q.inFlight.dispatched(task)
+	q.charge(task, q.costModel.Estimate(task))
+	return
+}
+
+// candidates returns the effective priority to serve next, and the fairness keys allowed with a
+// task at that priority along with the (unraised) priority of the task to serve for each.
+func (q *priorityFairnessTaskQueue) candidates(allow func(fairnessKey string) bool) (level Priority, candidates map[string]Priority) {
+	if q.aging.IsZero() {
+		queued := func(p Priority) bool {
+			for key := range q.storage[p] {
+				if allow(key) {
+					return true
+				}
+			}
+			return false
+		}
+		var ok bool
+		if level, ok = q.nextLevel(queued); !ok {
+			return
+		}
+		candidates = make(map[string]Priority, len(q.storage[level]))
+		for key := range q.storage[level] {
+			if allow(key) {
+				candidates[key] = level
+			}
+		}
+		return
+	}
+
+	now := q.clock.Now()
+	type candidate struct {
+		priority Priority
+		created  time.Time
+	}
+	var byLevel [5]map[string]candidate
 	for _, p := range []Priority{P0, P1, P2, P3, P4} {
-		if len(q.storage[p]) == 0 {
+		for key, tasks := range q.storage[p] {
+			if !allow(key) {
+				continue
+			}
+			// the oldest task of each key and priority is the one raised the most.
+			head, _ := tasks.Peek()
+			headLevel := q.aging.EffectivePriority(head, now)
+			if byLevel[headLevel] == nil {
+				byLevel[headLevel] = make(map[string]candidate)
+			}
+			if current, ok := byLevel[headLevel][key]; !ok || head.CreatedUTC.Before(current.created) {
+				byLevel[headLevel][key] = candidate{priority: p, created: head.CreatedUTC}
+			}
+		}
+	}
+	level, ok := q.nextLevel(func(p Priority) bool { return len(byLevel[p]) > 0 })
+	if !ok {
+		return
+	}
+	candidates = make(map[string]Priority, len(byLevel[level]))
+	for key, c := range byLevel[level] {
+		candidates[key] = c.priority
+	}
+	return
+}
+
+// nextLevel returns the priority to serve next of those with tasks queued, which is the highest,
+// or with class weights one chosen at random in proportion to their weights.
+func (q *priorityFairnessTaskQueue) nextLevel(queued func(Priority) bool) (level Priority, ok bool) {
+	weights := make(map[Priority]int)
+	for _, p := range priorities {
+		if !queued(p) {
 			continue
 		}
-		fairnessKey := RandomKeyByWeight(q.r, filterMapBySharedKeys(q.fairnessKeyWeights, q.storage[p]))
-		task, ok = mapFirst(q.storage[p][fairnessKey])
 		if !ok {
-			return
+			level, ok = p, true
+			if len(q.classWeights) == 0 {
+				return
+			}
 		}
-		delete(q.storage[p][fairnessKey], task.ID)
-		if len(q.storage[p][fairnessKey]) == 0 {
-			delete(q.storage[p], fairnessKey)
+		weight, hasWeight := q.classWeights[p]
+		if !hasWeight {
+			weight = 1
+		}
+		if weight > 0 {
+			weights[p] = weight
 		}
-		q.len--
-		return
+	}
+	if len(weights) > 0 {
+		level = RandomKeyByWeight(q.r, priorities, weights)
 	}
 	return
 }
 
+// deleteLane removes the (empty) tasks of a fairness key at a priority, and the
+// fairness key from the sorted keys if it has no tasks left at any priority.
+func (q *priorityFairnessTaskQueue) deleteLane(p Priority, fairnessKey string) {
+	delete(q.storage[p], fairnessKey)
+	for _, lanes := range q.storage {
+		if _, ok := lanes[fairnessKey]; ok {
+			return
+		}
+	}
+	if index, found := slices.BinarySearch(q.keys, fairnessKey); found {
+		q.keys = slices.Delete(q.keys, index, index+1)
+	}
+}
+
+// OnComplete implements [CompletionObserver].
+func (q *priorityFairnessTaskQueue) OnComplete(t *Task) {
+	q.inFlight.completed(t)
+	if q.costModel == CostModelTaskCount {
+		return
+	}
+	if cost, ok := q.costs[t.FairnessKey]; ok {
+		correction := q.costModel.Correction(t)
+		cost.total += correction
+		q.totalCost.total += correction
+	}
+}
+
+func (q *priorityFairnessTaskQueue) charge(t *Task, cost float64) {
+	if q.costModel == CostModelTaskCount {
+		return
+	}
+	rc, ok := q.costs[t.FairnessKey]
+	if !ok {
+		rc = new(runningCost)
+		q.costs[t.FairnessKey] = rc
+	}
+	rc.total += cost
+	rc.count++
+	q.totalCost.total += cost
+	q.totalCost.count++
+}
+
+// effectiveWeights divides each key's fairness weight by its average cost per task.
+//
+// Keys that have not been charged yet are assumed to cost the average across all keys.
+func (q *priorityFairnessTaskQueue) effectiveWeights(weights map[string]float64) map[string]float64 {
+	if q.costModel == CostModelTaskCount {
+		return weights
+	}
+	fallback := q.totalCost.avg()
+	for key, weight := range weights {
+		avg := q.costs[key].avg()
+		if avg <= 0 {
+			avg = fallback
+		}
+		if avg > 0 {
+			weights[key] = weight / avg
+		}
+	}
+	return weights
+}
+
+// runningCost is the total cost charged to a fairness key and the number of tasks it was charged for.
+type runningCost struct {
+	total float64
+	count int
+}
+
+func (rc *runningCost) avg() float64 {
+	if rc == nil || rc.count == 0 {
+		return 0
+	}
+	return rc.total / float64(rc.count)
+}
+
 func filterMapBySharedKeys[K comparable, V0, V1 any](filterThis map[K]V0, byThat map[K]V1) map[K]V0 {
 	output := make(map[K]V0)
 	for key, value := range filterThis {
@@ -61,11 +265,3 @@ func filterMapBySharedKeys[K comparable, V0, V1 any](filterThis map[K]V0, byThat
 	}
 	return output
 }
-
-func mapFirst[K comparable, V any](m map[K]V) (v V, ok bool) {
-	for _, v = range m {
-		ok = true
-		return
-	}
-	return
-}
@@ -2,12 +2,13 @@ package sim
 
 import (
 	"math/rand/v2"
+	"slices"
 	"testing"
 )
 
 func Test_PriorityFairnessTaskQueue(t *testing.T) {
 	r := rand.NewPCG(123, 123)
-	rq := NewPriorityFairnessTaskQueue(rand.New(r))
+	rq := NewPriorityFairnessTaskQueue(rand.New(r), nil, CostModelTaskCount, AgingPolicy{}, nil)
 
 	rq.Push(Task{ID: NewUUID(), FairnessKey: "high", Fairness: 70})
 	rq.Push(Task{ID: NewUUID(), FairnessKey: "high", Fairness: 70})
@@ -39,3 +40,55 @@ func Test_PriorityFairnessTaskQueue(t *testing.T) {
 		t.Fail()
 	}
 }
+
+func Test_PriorityFairnessTaskQueue_keys(t *testing.T) {
+	rq := NewPriorityFairnessTaskQueue(rand.New(rand.NewPCG(123, 123)), nil, CostModelTaskCount, AgingPolicy{}, nil).(*priorityFairnessTaskQueue)
+
+	rq.Push(Task{ID: NewUUID(), FairnessKey: "b", Fairness: 1, Priority: P2})
+	rq.Push(Task{ID: NewUUID(), FairnessKey: "a", Fairness: 1, Priority: P2})
+	rq.Push(Task{ID: NewUUID(), FairnessKey: "b", Fairness: 1, Priority: P3})
+	if !slices.Equal(rq.keys, []string{"a", "b"}) {
+		t.Errorf("expect keys to be [a b], was %v", rq.keys)
+		t.Fail()
+	}
+	for x := 0; x < 2; x++ {
+		_, _ = rq.Pull()
+	}
+	// "b" still has a task at P3.
+	if !slices.Equal(rq.keys, []string{"b"}) {
+		t.Errorf("expect keys to be [b], was %v", rq.keys)
+		t.Fail()
+	}
+	_, _ = rq.Pull()
+	if len(rq.keys) != 0 {
+		t.Errorf("expect no keys, was %v", rq.keys)
+		t.Fail()
+	}
+}
+
+func Test_PriorityFairnessTaskQueue_classWeights(t *testing.T) {
+	rq := NewPriorityFairnessTaskQueue(rand.New(rand.NewPCG(1, 2)), nil, CostModelTaskCount, AgingPolicy{}, map[Priority]int{P2: 3, P4: 1})
+	for x := 0; x < 1000; x++ {
+		for _, p := range []Priority{P0, P1, P2, P3, P4} {
+			rq.Push(Task{ID: NewUUID(), Priority: p, FairnessKey: p.String(), Fairness: 1})
+		}
+	}
+
+	counts := make(map[Priority]int)
+	for x := 0; x < 1400; x++ {
+		task, _ := rq.Pull()
+		counts[task.Priority]++
+	}
+	// the priorities without a class weight have a weight of 1, i.e. 200 of the dispatches each.
+	for _, p := range []Priority{P0, P1, P3, P4} {
+		if counts[p] < 150 || counts[p] > 250 {
+			t.Errorf("expect %v to be served ~200 times, was %v", p, counts)
+			t.Fail()
+		}
+	}
+	if counts[P2] < 540 || counts[P2] > 660 {
+		t.Errorf("expect P2 to be served ~600 times, was %v", counts)
+		t.Fail()
+	}
+
+}
@@ -1,24 +1,70 @@
 package sim
 
-func NewPrioritySortedTaskQueue() TaskQueue {
-	return &prioritySortedTaskQueue{
-		storage: NewPriorityQueue[*Task](),
+// NewPrioritySortedTaskQueue returns a new priority sorted task queue.
+//
+// Tasks are served strictly by priority, oldest first within a priority, where
+// the aging policy (if any) raises the priority of tasks as they wait.
+func NewPrioritySortedTaskQueue(c Clock, aging AgingPolicy) TaskQueue {
+	q := &prioritySortedTaskQueue{
+		clock: c,
+		aging: aging,
 	}
+	for p := range q.storage {
+		q.storage[p] = newKeyedTaskFifo()
+	}
+	return q
 }
 
 type prioritySortedTaskQueue struct {
-	storage *PriorityQueue[*Task]
+	len     int
+	storage [5]*keyedTaskFifo
+	clock   Clock
+	aging   AgingPolicy
 }
 
 func (q *prioritySortedTaskQueue) Len() int {
-	return q.storage.Len()
+	return q.len
 }
 
 func (q *prioritySortedTaskQueue) Push(t Task) {
-	q.storage.Push(&t, int(t.Priority))
+	q.storage[t.Priority].Push(&t)
+	q.len++
 }
 
 func (q *prioritySortedTaskQueue) Pull() (task *Task, ok bool) {
-	task, _, ok = q.storage.Pop()
+	return q.PullFiltered(allowAllFairnessKeys)
+}
+
+// PullFiltered implements [FilteredTaskQueue].
+func (q *prioritySortedTaskQueue) PullFiltered(allow func(fairnessKey string) bool) (task *Task, ok bool) {
+	next, level := -1, Priority(0)
+	if q.aging.IsZero() {
+		for p := range q.storage {
+			if _, hasHead := q.storage[p].Peek(allow); hasHead {
+				next, level = p, Priority(p)
+				break
+			}
+		}
+	} else {
+		// the oldest task of each priority is the one raised the most.
+		now := q.clock.Now()
+		var oldest *Task
+		for p := range q.storage {
+			head, hasHead := q.storage[p].Peek(allow)
+			if !hasHead {
+				continue
+			}
+			headLevel := q.aging.EffectivePriority(head, now)
+			if next < 0 || headLevel < level || (headLevel == level && head.CreatedUTC.Before(oldest.CreatedUTC)) {
+				next, level, oldest = p, headLevel, head
+			}
+		}
+	}
+	if next < 0 {
+		return
+	}
+	task, ok = q.storage[next].Pop(allow)
+	task.Boost = int(task.Priority - level)
+	q.len--
 	return
 }
@@ -2,11 +2,25 @@ package sim
 
 import "math/rand/v2"
 
-func RandomKeyByWeight[K comparable, V Operatable](r *rand.Rand, keyWeights map[K]V) K {
-	total := SumValues(keyWeights)
+// RandomKeyByWeight returns one of the given keys at random in proportion to its weight,
+// where keys without a (positive) weight are never returned.
+//
+// Keys are visited in the given order rather than map order, such that callers passing them
+// sorted get the same keys from the same random source.
+func RandomKeyByWeight[K comparable, V Operatable](r *rand.Rand, keys []K, keyWeights map[K]V) K {
+	var total V
+	for _, key := range keys {
+		if w := keyWeights[key]; w > 0 {
+			total += w
+		}
+	}
 	nf := r.Float64()
 	var accum float64
-	for key, w := range keyWeights {
+	for _, key := range keys {
+		w := keyWeights[key]
+		if w <= 0 {
+			continue
+		}
 		accum += float64(w) / float64(total)
 		if nf <= accum {
 			return key
@@ -0,0 +1,48 @@
+package sim
+
+import (
+	"math"
+	"math/rand/v2"
+)
+
+// RandomPoisson returns a random value with a poisson distribution with a given mean.
+//
+// Small means use Knuth's multiplication method, and larger means use the
+// transformed rejection method (PTRS) from Hörmann (1993), which is O(1).
+func RandomPoisson(r *rand.Rand, mean float64) int {
+	if mean <= 0 {
+		return 0
+	}
+	if mean < 10 {
+		limit := math.Exp(-mean)
+		var k int
+		p := r.Float64()
+		for p > limit {
+			k++
+			p *= r.Float64()
+		}
+		return k
+	}
+	smu := math.Sqrt(mean)
+	b := 0.931 + 2.53*smu
+	a := -0.059 + 0.02483*b
+	invAlpha := 1.1239 + 1.1328/(b-3.4)
+	vr := 0.9277 - 3.6224/(b-2)
+	logMean := math.Log(mean)
+	for {
+		u := r.Float64() - 0.5
+		v := r.Float64()
+		us := 0.5 - math.Abs(u)
+		k := math.Floor((2*a/us+b)*u + mean + 0.43)
+		if us >= 0.07 && v <= vr {
+			return int(k)
+		}
+		if k < 0 || (us < 0.013 && v > us) {
+			continue
+		}
+		lgamma, _ := math.Lgamma(k + 1)
+		if math.Log(v)+math.Log(invAlpha)-math.Log(a/(us*us)+b) <= -mean+k*logMean-lgamma {
+			return int(k)
+		}
+	}
+}
@@ -17,6 +17,8 @@ func NewRateLimiter(c Clock, limitActions uint32, limitQuantum time.Duration) Ra
 type RateLimiter interface {
 	Allow() bool
 	Commit()
+	// CommitN commits a (potentially fractional) number of actions; negative values refund actions.
+	CommitN(float64)
 }
 
 type rateLimiter struct {
@@ -43,5 +45,9 @@ func (rl *rateLimiter) Allow() bool {
 }
 
 func (rl *rateLimiter) Commit() {
-	rl.tokens -= 1.0
+	rl.CommitN(1.0)
+}
+
+func (rl *rateLimiter) CommitN(actions float64) {
+	rl.tokens -= actions
 }
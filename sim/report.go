@@ -0,0 +1,297 @@
+package sim
+
+import (
+	"html/template"
+	"io"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// Report is a self-contained html report of a simulation run.
+type Report struct {
+	Title string
+	// Settings summarize the configuration of the run, in order.
+	Settings []ReportSetting
+	Results  SimulationResults
+}
+
+// ReportSetting is a named configuration value shown in a report.
+type ReportSetting struct {
+	Name  string
+	Value string
+}
+
+// WriteHTML writes the report as a single html file with inline svg charts.
+func (r Report) WriteHTML(w io.Writer) error {
+	return reportTemplate.Execute(w, r.view())
+}
+
+type reportView struct {
+	Title         string
+	Settings      []ReportSetting
+	Results       SimulationResults
+	FairnessKeys  []reportRow
+	FairnessPaths []reportRow
+	Priorities    []reportRow
+	Latencies     []reportLatency
+	Deadlines     []reportDeadlines
+
+	QueueLength         template.HTML
+	Throughput          template.HTML
+	QueuedByFairnessKey template.HTML
+	QueuedByPriority    template.HTML
+}
+
+type reportLatency struct {
+	Name    string
+	Latency LatencyDistribution
+}
+
+type reportDeadlines struct {
+	Name      string
+	Deadlines DeadlineResults
+}
+
+type reportRow struct {
+	Name            string
+	Color           string
+	Count           int
+	QueuedAvg       time.Duration
+	QueuedP50       time.Duration
+	QueuedP95       time.Duration
+	QueuedP99       time.Duration
+	WorkerTimeShare float64
+	Boosted         int
+
+	Share             float64
+	ConfiguredShare   float64
+	LongestStarvation time.Duration
+}
+
+func (r Report) view() (view reportView) {
+	res := r.Results
+	view.Title = r.Title
+	view.Settings = r.Settings
+	view.Results = res
+	view.Latencies = []reportLatency{
+		{"queued", res.Latencies.Queued},
+		{"service", res.Latencies.Service},
+		{"end-to-end", res.Latencies.EndToEnd},
+	}
+
+	var totalWorkerTime time.Duration
+	for _, workerTime := range res.WorkerTimeByFairnessKey {
+		totalWorkerTime += workerTime
+	}
+	var fairnessKeySeries []chartSeries
+	for index, key := range fairnessKeysOf(res) {
+		row := reportRow{
+			Name:      key,
+			Color:     chartColor(index),
+			Count:     res.CountByFairnessKey[key],
+			QueuedAvg: res.QueuedAvgByFairnessKey[key],
+			QueuedP50: res.QueuedP50ByFairnessKey[key],
+			QueuedP95: res.QueuedP95ByFairnessKey[key],
+			QueuedP99: res.QueuedP99ByFairnessKey[key],
+
+			Share:             100 * res.Fairness.ShareByFairnessKey[key],
+			ConfiguredShare:   100 * res.Fairness.ConfiguredShareByFairnessKey[key],
+			LongestStarvation: res.Fairness.LongestStarvationByFairnessKey[key],
+		}
+		if totalWorkerTime > 0 {
+			row.WorkerTimeShare = 100 * float64(res.WorkerTimeByFairnessKey[key]) / float64(totalWorkerTime)
+		}
+		view.FairnessKeys = append(view.FairnessKeys, row)
+		fairnessKeySeries = append(fairnessKeySeries, cdfSeries(key, row.Color, res.QueuedQuantilesByFairnessKey[key]))
+	}
+
+	for _, path := range sortedMapKeys(res.LatenciesByFairnessPath) {
+		row := reportRow{
+			Name:      path,
+			Count:     res.CountByFairnessPath[path],
+			QueuedAvg: res.LatenciesByFairnessPath[path].Queued.Avg,
+			Share:     100 * res.Fairness.ShareByFairnessPath[path],
+		}
+		if totalWorkerTime > 0 {
+			row.WorkerTimeShare = 100 * float64(res.WorkerTimeByFairnessPath[path]) / float64(totalWorkerTime)
+		}
+		view.FairnessPaths = append(view.FairnessPaths, row)
+	}
+
+	priorities := make([]Priority, 0, len(res.CountByPriority))
+	for p := range res.CountByPriority {
+		priorities = append(priorities, p)
+	}
+	slices.Sort(priorities)
+	var prioritySeries []chartSeries
+	for index, p := range priorities {
+		row := reportRow{
+			Name:      p.String(),
+			Color:     chartColor(index),
+			Count:     res.CountByPriority[p],
+			QueuedAvg: res.QueuedAvgByPriority[p],
+			QueuedP50: res.QueuedP50ByPriority[p],
+			QueuedP95: res.QueuedP95ByPriority[p],
+			QueuedP99: res.QueuedP99ByPriority[p],
+			Boosted:   res.BoostedByPriority[p],
+		}
+		view.Priorities = append(view.Priorities, row)
+		prioritySeries = append(prioritySeries, cdfSeries(row.Name, row.Color, res.QueuedQuantilesByPriority[p]))
+	}
+
+	if res.Deadlines.Tasks > 0 {
+		view.Deadlines = append(view.Deadlines, reportDeadlines{"all", res.Deadlines})
+		for _, p := range priorities {
+			if deadlines, ok := res.DeadlinesByPriority[p]; ok {
+				view.Deadlines = append(view.Deadlines, reportDeadlines{p.String(), deadlines})
+			}
+		}
+		for _, key := range fairnessKeysOf(res) {
+			if deadlines, ok := res.DeadlinesByFairnessKey[key]; ok {
+				view.Deadlines = append(view.Deadlines, reportDeadlines{strconv.Quote(key), deadlines})
+			}
+		}
+	}
+
+	queueLength := chartSeries{Name: "queue length", Color: chartColor(0)}
+	for _, sample := range res.QueueLengths {
+		queueLength.Points = append(queueLength.Points, chartPoint{X: sample.Elapsed.Minutes(), Y: float64(sample.Length)})
+	}
+	view.QueueLength = svgChart{XLabel: "elapsed (minutes)", YLabel: "tasks queued", Series: []chartSeries{queueLength}}.SVG()
+
+	throughput := chartSeries{Name: "tasks per second", Color: chartColor(0)}
+	for _, bucket := range res.Buckets {
+		throughput.Points = append(throughput.Points, chartPoint{X: (bucket.Start + bucket.End).Minutes() / 2, Y: bucket.Throughput})
+	}
+	view.Throughput = svgChart{XLabel: "elapsed (minutes)", YLabel: "tasks per second", Bars: true, Series: []chartSeries{throughput}}.SVG()
+
+	view.QueuedByFairnessKey = svgChart{XLabel: "queued (seconds)", YLabel: "percentile", XMax: cdfXMax(fairnessKeySeries), Series: fairnessKeySeries}.SVG()
+	view.QueuedByPriority = svgChart{XLabel: "queued (seconds)", YLabel: "percentile", XMax: cdfXMax(prioritySeries), Series: prioritySeries}.SVG()
+	return
+}
+
+// cdfSeries returns the cumulative distribution of queued times from their whole percentiles.
+func cdfSeries(name, color string, quantiles []time.Duration) chartSeries {
+	series := chartSeries{Name: name, Color: color}
+	for percent, queued := range quantiles {
+		series.Points = append(series.Points, chartPoint{X: queued.Seconds(), Y: float64(percent)})
+	}
+	return series
+}
+
+// cdfXMax returns the largest p99 of the series, such that a few outliers don't flatten the chart.
+func cdfXMax(series []chartSeries) (xMax float64) {
+	for _, s := range series {
+		if len(s.Points) > 99 {
+			xMax = max(xMax, s.Points[99].X)
+		}
+	}
+	return
+}
+
+func formatReportDuration(d time.Duration) string {
+	return d.Round(time.Millisecond).String()
+}
+
+var reportTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"duration":   formatReportDuration,
+	"percentile": FormatPercentile,
+	"percent":    func(fraction float64) float64 { return 100 * fraction },
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{ .Title }}</title>
+<style>
+body { font-family: sans-serif; margin: 2em auto; max-width: 760px; color: #222; }
+table { border-collapse: collapse; margin-bottom: 1em; }
+th, td { padding: 2px 10px; text-align: right; border-bottom: 1px solid #eee; }
+th:first-child, td:first-child { text-align: left; }
+.swatch { display: inline-block; width: 10px; height: 10px; margin-right: 4px; }
+</style>
+</head>
+<body>
+<h1>{{ .Title }}</h1>
+
+<h2>Configuration</h2>
+<table>
+{{- range .Settings }}
+<tr><td>{{ .Name }}</td><td>{{ .Value }}</td></tr>
+{{- end }}
+</table>
+
+<h2>Summary</h2>
+<table>
+<tr><td>tasks processed</td><td>{{ .Results.TasksProcessed }}</td></tr>
+<tr><td>queued avg</td><td>{{ duration .Results.QueuedAvg }}</td></tr>
+<tr><td>queued p50</td><td>{{ duration .Results.QueuedP50 }}</td></tr>
+<tr><td>queued p95</td><td>{{ duration .Results.QueuedP95 }}</td></tr>
+<tr><td>queued p99</td><td>{{ duration .Results.QueuedP99 }}</td></tr>
+</table>
+
+<h2>Latency</h2>
+<table>
+<tr><th>latency</th><th>count</th><th>avg</th>{{ range .Results.Percentiles }}<th>{{ percentile . }}</th>{{ end }}<th>max</th></tr>
+{{- range .Latencies }}
+<tr><td>{{ .Name }}</td><td>{{ .Latency.Count }}</td><td>{{ duration .Latency.Avg }}</td>{{ range .Latency.Percentiles }}<td>{{ duration .Value }}</td>{{ end }}<td>{{ duration .Latency.Max }}</td></tr>
+{{- end }}
+</table>
+
+<h2>Fairness</h2>
+<table>
+<tr><td>jain index</td><td>{{ printf "%.3f" .Results.Fairness.JainIndex }}</td></tr>
+<tr><td>max share deviation</td><td>{{ printf "%.1f%%" (percent .Results.Fairness.MaxShareDeviation) }}</td></tr>
+<tr><td>longest starvation</td><td>{{ duration .Results.Fairness.LongestStarvation }}{{ with .Results.Fairness.LongestStarvationFairnessKey }} ({{ printf "%q" . }}){{ end }}</td></tr>
+</table>
+<table>
+<tr><th>fairness key</th><th>share</th><th>configured share</th><th>longest starvation</th></tr>
+{{- range .FairnessKeys }}
+<tr><td><span class="swatch" style="background: {{ .Color }}"></span>{{ printf "%q" .Name }}</td><td>{{ printf "%.1f%%" .Share }}</td><td>{{ printf "%.1f%%" .ConfiguredShare }}</td><td>{{ duration .LongestStarvation }}</td></tr>
+{{- end }}
+</table>
+{{- if .FairnessPaths }}
+<table>
+<tr><th>fairness path</th><th>count</th><th>share</th><th>worker time</th><th>queued avg</th></tr>
+{{- range .FairnessPaths }}
+<tr><td>{{ printf "%q" .Name }}</td><td>{{ .Count }}</td><td>{{ printf "%.1f%%" .Share }}</td><td>{{ printf "%.1f%%" .WorkerTimeShare }}</td><td>{{ duration .QueuedAvg }}</td></tr>
+{{- end }}
+</table>
+{{- end }}
+{{- if .Deadlines }}
+
+<h2>Deadlines</h2>
+<table>
+<tr><th>deadlines</th><th>tasks</th><th>missed</th><th>miss rate</th></tr>
+{{- range .Deadlines }}
+<tr><td>{{ .Name }}</td><td>{{ .Deadlines.Tasks }}</td><td>{{ .Deadlines.Missed }}</td><td>{{ printf "%.2f%%" (percent .Deadlines.MissRate) }}</td></tr>
+{{- end }}
+</table>
+{{- end }}
+
+<h2>Queue length</h2>
+{{ .QueueLength }}
+
+<h2>Throughput</h2>
+{{ .Throughput }}
+
+<h2>Queueing delay by fairness key</h2>
+<table>
+<tr><th>fairness key</th><th>count</th><th>avg</th><th>p50</th><th>p95</th><th>p99</th><th>worker time</th></tr>
+{{- range .FairnessKeys }}
+<tr><td><span class="swatch" style="background: {{ .Color }}"></span>{{ printf "%q" .Name }}</td><td>{{ .Count }}</td><td>{{ duration .QueuedAvg }}</td><td>{{ duration .QueuedP50 }}</td><td>{{ duration .QueuedP95 }}</td><td>{{ duration .QueuedP99 }}</td><td>{{ printf "%.1f%%" .WorkerTimeShare }}</td></tr>
+{{- end }}
+</table>
+{{ .QueuedByFairnessKey }}
+
+<h2>Queueing delay by priority</h2>
+<table>
+<tr><th>priority</th><th>count</th><th>boosted</th><th>avg</th><th>p50</th><th>p95</th><th>p99</th></tr>
+{{- range .Priorities }}
+<tr><td><span class="swatch" style="background: {{ .Color }}"></span>{{ .Name }}</td><td>{{ .Count }}</td><td>{{ .Boosted }}</td><td>{{ duration .QueuedAvg }}</td><td>{{ duration .QueuedP50 }}</td><td>{{ duration .QueuedP95 }}</td><td>{{ duration .QueuedP99 }}</td></tr>
+{{- end }}
+</table>
+{{ .QueuedByPriority }}
+</body>
+</html>
+`))
@@ -0,0 +1,47 @@
+package sim
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_Report_WriteHTML(t *testing.T) {
+	res := testResults()
+	res.QueuedQuantilesByFairnessKey = map[string][]time.Duration{"a": make([]time.Duration, 101)}
+	res.QueueLengths = []QueueLengthSample{{Elapsed: 0, Length: 0}, {Elapsed: time.Minute, Length: 5}}
+
+	buffer := new(bytes.Buffer)
+	report := Report{
+		Title:    "fairness <experiment>",
+		Settings: []ReportSetting{{Name: "task queue type", Value: "drr"}},
+		Results:  res,
+	}
+	if err := report.WriteHTML(buffer); err != nil {
+		t.Errorf("expect write error to be nil, was %v", err)
+		t.FailNow()
+	}
+	output := buffer.String()
+	if strings.Count(output, "<svg") != 4 {
+		t.Errorf("expect four inline svg charts, was %d", strings.Count(output, "<svg"))
+		t.Fail()
+	}
+	if !strings.Contains(output, "fairness &lt;experiment&gt;") {
+		t.Errorf("expect the title to be escaped")
+		t.Fail()
+	}
+	if strings.Contains(output, "ZgotmplZ") || strings.Contains(output, "<script") {
+		t.Errorf("expect no unsafe template values or scripts")
+		t.Fail()
+	}
+}
+
+func Test_niceCeil(t *testing.T) {
+	for value, expected := range map[float64]float64{0.7: 1, 1: 1, 1.2: 2, 2.2: 2.5, 3: 5, 7: 10, 1234: 2000} {
+		if actual := niceCeil(value); actual != expected {
+			t.Errorf("expect niceCeil(%v) to be %v, was %v", value, expected, actual)
+			t.Fail()
+		}
+	}
+}
@@ -0,0 +1,555 @@
+package sim
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ResultsSchemaVersion is the version of the json and csv results schema, and is
+// incremented whenever a field or metric is renamed, removed or changes meaning.
+//
+// Adding fields or metrics does not change the version.
+//
+// Version 2 takes percentiles by nearest rank from the latency histograms, where version 1
+// interpolated between the two nearest of the sorted durations.
+const ResultsSchemaVersion = 2
+
+// WriteResultsJSON writes the results as a json document.
+//
+// Durations are in seconds, and priorities are rendered as e.g. "P2".
+func WriteResultsJSON(w io.Writer, res SimulationResults) error {
+	doc := resultsDocument{
+		SchemaVersion:          ResultsSchemaVersion,
+		ElapsedSeconds:         res.ElapsedTime.Seconds(),
+		resultsSummaryDocument: newResultsSummaryDocument(res),
+	}
+	for _, bucket := range res.Buckets {
+		doc.Buckets = append(doc.Buckets, bucketDocument{
+			StartSeconds:           bucket.Start.Seconds(),
+			EndSeconds:             bucket.End.Seconds(),
+			QueueLength:            bucket.QueueLength,
+			Throughput:             bucket.Throughput,
+			resultsSummaryDocument: newResultsSummaryDocument(bucket.Results),
+		})
+	}
+	for _, phase := range res.Phases {
+		doc.Phases = append(doc.Phases, phaseDocument{
+			Name:                   phase.Name,
+			StartSeconds:           phase.Start.Seconds(),
+			EndSeconds:             phase.End.Seconds(),
+			QueueLength:            phase.QueueLength,
+			resultsSummaryDocument: newResultsSummaryDocument(phase.Results),
+		})
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(doc)
+}
+
+// WriteResultsCSV writes the results as csv in the long format of [SimulationResults.Metrics],
+// with a leading schema version column.
+func WriteResultsCSV(w io.Writer, res SimulationResults) error {
+	cw := csv.NewWriter(w)
+	_ = cw.Write([]string{"schema_version", "scope", "scope_key", "dimension", "dimension_key", "metric", "value"})
+	schemaVersion := strconv.Itoa(ResultsSchemaVersion)
+	for _, m := range res.Metrics() {
+		_ = cw.Write([]string{
+			schemaVersion,
+			m.Scope,
+			m.ScopeKey,
+			m.Dimension,
+			m.DimensionKey,
+			m.Name,
+			strconv.FormatFloat(m.Value, 'f', -1, 64),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// Metric is a single value of the results.
+type Metric struct {
+	// Scope is one of "run", "bucket" or "phase".
+	Scope string
+	// ScopeKey is the end offset of the bucket in seconds, or the name of the phase.
+	ScopeKey string
+	// Dimension is one of "all", "priority" or "fairness_key".
+	Dimension    string
+	DimensionKey string
+	Name         string
+	// Value is the value of the metric, where durations are in seconds.
+	Value float64
+}
+
+// Metrics returns the results as a flat list of metrics in a stable order.
+func (res SimulationResults) Metrics() (output []Metric) {
+	output = appendSummaryMetrics(output, "run", "", res)
+	for _, bucket := range res.Buckets {
+		scopeKey := strconv.FormatFloat(bucket.End.Seconds(), 'f', -1, 64)
+		output = append(output,
+			Metric{Scope: "bucket", ScopeKey: scopeKey, Dimension: "all", Name: "start_seconds", Value: bucket.Start.Seconds()},
+			Metric{Scope: "bucket", ScopeKey: scopeKey, Dimension: "all", Name: "queue_length", Value: float64(bucket.QueueLength)},
+			Metric{Scope: "bucket", ScopeKey: scopeKey, Dimension: "all", Name: "throughput", Value: bucket.Throughput},
+		)
+		output = appendSummaryMetrics(output, "bucket", scopeKey, bucket.Results)
+	}
+	for _, phase := range res.Phases {
+		output = append(output,
+			Metric{Scope: "phase", ScopeKey: phase.Name, Dimension: "all", Name: "start_seconds", Value: phase.Start.Seconds()},
+			Metric{Scope: "phase", ScopeKey: phase.Name, Dimension: "all", Name: "end_seconds", Value: phase.End.Seconds()},
+			Metric{Scope: "phase", ScopeKey: phase.Name, Dimension: "all", Name: "queue_length", Value: float64(phase.QueueLength)},
+		)
+		output = appendSummaryMetrics(output, "phase", phase.Name, phase.Results)
+	}
+	return
+}
+
+func appendSummaryMetrics(output []Metric, scope, scopeKey string, res SimulationResults) []Metric {
+	add := func(dimension, dimensionKey, name string, value float64) {
+		output = append(output, Metric{Scope: scope, ScopeKey: scopeKey, Dimension: dimension, DimensionKey: dimensionKey, Name: name, Value: value})
+	}
+	addQueued := func(dimension, dimensionKey string, avg, p50, p95, p99 time.Duration) {
+		add(dimension, dimensionKey, "queued_avg_seconds", avg.Seconds())
+		add(dimension, dimensionKey, "queued_p50_seconds", p50.Seconds())
+		add(dimension, dimensionKey, "queued_p95_seconds", p95.Seconds())
+		add(dimension, dimensionKey, "queued_p99_seconds", p99.Seconds())
+	}
+
+	addLatencies := func(dimension, dimensionKey string, latencies Latencies) {
+		for _, latency := range []struct {
+			name string
+			ld   LatencyDistribution
+		}{{"queued", latencies.Queued}, {"service", latencies.Service}, {"end_to_end", latencies.EndToEnd}} {
+			if latency.name != "queued" {
+				add(dimension, dimensionKey, latency.name+"_avg_seconds", latency.ld.Avg.Seconds())
+			}
+			add(dimension, dimensionKey, latency.name+"_max_seconds", latency.ld.Max.Seconds())
+			for _, p := range latency.ld.Percentiles {
+				// the queued p50, p95 and p99 are always included.
+				if latency.name == "queued" && (p.Percentile == 50 || p.Percentile == 95 || p.Percentile == 99) {
+					continue
+				}
+				add(dimension, dimensionKey, latency.name+"_"+strings.ReplaceAll(FormatPercentile(p.Percentile), ".", "_")+"_seconds", p.Value.Seconds())
+			}
+		}
+	}
+
+	add("all", "", "tasks_processed", float64(res.TasksProcessed))
+	if scope == "run" {
+		add("all", "", "elapsed_seconds", res.ElapsedTime.Seconds())
+	}
+	addQueued("all", "", res.QueuedAvg, res.QueuedP50, res.QueuedP95, res.QueuedP99)
+	addLatencies("all", "", res.Latencies)
+	add("all", "", "jain_index", res.Fairness.JainIndex)
+	add("all", "", "max_share_deviation", res.Fairness.MaxShareDeviation)
+	add("all", "", "longest_starvation_seconds", res.Fairness.LongestStarvation.Seconds())
+	addDeadlines := func(dimension, dimensionKey string, deadlines DeadlineResults, ok bool) {
+		if ok && deadlines.Tasks > 0 {
+			add(dimension, dimensionKey, "deadline_tasks", float64(deadlines.Tasks))
+			add(dimension, dimensionKey, "deadline_missed", float64(deadlines.Missed))
+			add(dimension, dimensionKey, "deadline_miss_rate", deadlines.MissRate())
+		}
+	}
+	addDeadlines("all", "", res.Deadlines, true)
+
+	priorities := make([]Priority, 0, len(res.CountByPriority))
+	for p := range res.CountByPriority {
+		priorities = append(priorities, p)
+	}
+	slices.Sort(priorities)
+	for _, p := range priorities {
+		add("priority", p.String(), "count", float64(res.CountByPriority[p]))
+		add("priority", p.String(), "boosted", float64(res.BoostedByPriority[p]))
+		deadlines, ok := res.DeadlinesByPriority[p]
+		addDeadlines("priority", p.String(), deadlines, ok)
+		addQueued("priority", p.String(), res.QueuedAvgByPriority[p], res.QueuedP50ByPriority[p], res.QueuedP95ByPriority[p], res.QueuedP99ByPriority[p])
+		addLatencies("priority", p.String(), res.LatenciesByPriority[p])
+	}
+	for _, key := range fairnessKeysOf(res) {
+		add("fairness_key", key, "count", float64(res.CountByFairnessKey[key]))
+		addQueued("fairness_key", key, res.QueuedAvgByFairnessKey[key], res.QueuedP50ByFairnessKey[key], res.QueuedP95ByFairnessKey[key], res.QueuedP99ByFairnessKey[key])
+		addLatencies("fairness_key", key, res.LatenciesByFairnessKey[key])
+		add("fairness_key", key, "worker_time_seconds", res.WorkerTimeByFairnessKey[key].Seconds())
+		add("fairness_key", key, "share", res.Fairness.ShareByFairnessKey[key])
+		add("fairness_key", key, "configured_share", res.Fairness.ConfiguredShareByFairnessKey[key])
+		add("fairness_key", key, "longest_starvation_seconds", res.Fairness.LongestStarvationByFairnessKey[key].Seconds())
+		deadlines, ok := res.DeadlinesByFairnessKey[key]
+		addDeadlines("fairness_key", key, deadlines, ok)
+		if res.ConcurrencyMaxByFairnessKey != nil {
+			add("fairness_key", key, "concurrency_max", float64(res.ConcurrencyMaxByFairnessKey[key]))
+			add("fairness_key", key, "concurrency_avg", res.ConcurrencyAvgByFairnessKey[key])
+		}
+	}
+	for _, path := range sortedMapKeys(res.LatenciesByFairnessPath) {
+		add("fairness_path", path, "count", float64(res.CountByFairnessPath[path]))
+		addLatencies("fairness_path", path, res.LatenciesByFairnessPath[path])
+		add("fairness_path", path, "worker_time_seconds", res.WorkerTimeByFairnessPath[path].Seconds())
+		add("fairness_path", path, "share", res.Fairness.ShareByFairnessPath[path])
+	}
+	return output
+}
+
+// fairnessKeysOf returns every fairness key that appears in the results, sorted.
+func fairnessKeysOf(res SimulationResults) []string {
+	keys := make(map[string]struct{})
+	for key := range res.CountByFairnessKey {
+		keys[key] = struct{}{}
+	}
+	for key := range res.QueuedAvgByFairnessKey {
+		keys[key] = struct{}{}
+	}
+	for key := range res.Fairness.LongestStarvationByFairnessKey {
+		keys[key] = struct{}{}
+	}
+	return sortedMapKeys(keys)
+}
+
+type resultsDocument struct {
+	SchemaVersion  int     `json:"schemaVersion"`
+	ElapsedSeconds float64 `json:"elapsedSeconds"`
+	resultsSummaryDocument
+	Buckets []bucketDocument `json:"buckets"`
+	Phases  []phaseDocument  `json:"phases,omitempty"`
+}
+
+type bucketDocument struct {
+	StartSeconds float64 `json:"startSeconds"`
+	EndSeconds   float64 `json:"endSeconds"`
+	QueueLength  int     `json:"queueLength"`
+	Throughput   float64 `json:"throughput"`
+	resultsSummaryDocument
+}
+
+type phaseDocument struct {
+	Name         string  `json:"name"`
+	StartSeconds float64 `json:"startSeconds"`
+	EndSeconds   float64 `json:"endSeconds"`
+	QueueLength  int     `json:"queueLength"`
+	resultsSummaryDocument
+}
+
+type resultsSummaryDocument struct {
+	TasksProcessed int                             `json:"tasksProcessed"`
+	Queued         queuedDocument                  `json:"queued"`
+	Latency        latenciesDocument               `json:"latency"`
+	Fairness       fairnessDocument                `json:"fairness"`
+	Deadlines      *deadlineDocument               `json:"deadlines,omitempty"`
+	ByPriority     map[string]priorityDocument     `json:"byPriority"`
+	ByFairnessKey  map[string]fairnessKeyDocument  `json:"byFairnessKey"`
+	ByFairnessPath map[string]fairnessPathDocument `json:"byFairnessPath,omitempty"`
+}
+
+type queuedDocument struct {
+	AvgSeconds float64 `json:"avgSeconds"`
+	P50Seconds float64 `json:"p50Seconds"`
+	P95Seconds float64 `json:"p95Seconds"`
+	P99Seconds float64 `json:"p99Seconds"`
+}
+
+type priorityDocument struct {
+	Count     int               `json:"count"`
+	Boosted   int               `json:"boosted"`
+	Queued    queuedDocument    `json:"queued"`
+	Latency   latenciesDocument `json:"latency"`
+	Deadlines *deadlineDocument `json:"deadlines,omitempty"`
+}
+
+type fairnessKeyDocument struct {
+	Count             int               `json:"count"`
+	Queued            queuedDocument    `json:"queued"`
+	Latency           latenciesDocument `json:"latency"`
+	WorkerTimeSeconds float64           `json:"workerTimeSeconds"`
+	Share             float64           `json:"share"`
+	ConfiguredShare   float64           `json:"configuredShare"`
+	// LongestStarvationSeconds is the longest time the key had tasks queued without one being dispatched.
+	LongestStarvationSeconds float64           `json:"longestStarvationSeconds"`
+	ConcurrencyMax           *int              `json:"concurrencyMax,omitempty"`
+	ConcurrencyAvg           *float64          `json:"concurrencyAvg,omitempty"`
+	Deadlines                *deadlineDocument `json:"deadlines,omitempty"`
+}
+
+type fairnessPathDocument struct {
+	Count             int               `json:"count"`
+	Latency           latenciesDocument `json:"latency"`
+	WorkerTimeSeconds float64           `json:"workerTimeSeconds"`
+	Share             float64           `json:"share"`
+}
+
+type deadlineDocument struct {
+	Tasks    int     `json:"tasks"`
+	Missed   int     `json:"missed"`
+	MissRate float64 `json:"missRate"`
+}
+
+// newDeadlineDocument returns nil if no tasks had a deadline, such that it is omitted.
+func newDeadlineDocument(deadlines DeadlineResults) *deadlineDocument {
+	if deadlines.Tasks == 0 {
+		return nil
+	}
+	return &deadlineDocument{Tasks: deadlines.Tasks, Missed: deadlines.Missed, MissRate: deadlines.MissRate()}
+}
+
+type fairnessDocument struct {
+	JainIndex                    float64 `json:"jainIndex"`
+	MaxShareDeviation            float64 `json:"maxShareDeviation"`
+	LongestStarvationSeconds     float64 `json:"longestStarvationSeconds"`
+	LongestStarvationFairnessKey string  `json:"longestStarvationFairnessKey,omitempty"`
+}
+
+type latenciesDocument struct {
+	Queued   latencyDocument `json:"queued"`
+	Service  latencyDocument `json:"service"`
+	EndToEnd latencyDocument `json:"endToEnd"`
+}
+
+type latencyDocument struct {
+	Count      int     `json:"count"`
+	AvgSeconds float64 `json:"avgSeconds"`
+	MaxSeconds float64 `json:"maxSeconds"`
+	// Percentiles are keyed by e.g. "p99.9".
+	Percentiles map[string]float64 `json:"percentiles"`
+}
+
+func newLatenciesDocument(latencies Latencies) latenciesDocument {
+	return latenciesDocument{
+		Queued:   newLatencyDocument(latencies.Queued),
+		Service:  newLatencyDocument(latencies.Service),
+		EndToEnd: newLatencyDocument(latencies.EndToEnd),
+	}
+}
+
+func newLatencyDocument(ld LatencyDistribution) latencyDocument {
+	doc := latencyDocument{
+		Count:       ld.Count,
+		AvgSeconds:  ld.Avg.Seconds(),
+		MaxSeconds:  ld.Max.Seconds(),
+		Percentiles: make(map[string]float64, len(ld.Percentiles)),
+	}
+	for _, p := range ld.Percentiles {
+		doc.Percentiles[FormatPercentile(p.Percentile)] = p.Value.Seconds()
+	}
+	return doc
+}
+
+func newQueuedDocument(avg, p50, p95, p99 time.Duration) queuedDocument {
+	return queuedDocument{
+		AvgSeconds: avg.Seconds(),
+		P50Seconds: p50.Seconds(),
+		P95Seconds: p95.Seconds(),
+		P99Seconds: p99.Seconds(),
+	}
+}
+
+func newResultsSummaryDocument(res SimulationResults) resultsSummaryDocument {
+	doc := resultsSummaryDocument{
+		TasksProcessed: res.TasksProcessed,
+		Queued:         newQueuedDocument(res.QueuedAvg, res.QueuedP50, res.QueuedP95, res.QueuedP99),
+		Latency:        newLatenciesDocument(res.Latencies),
+		Fairness: fairnessDocument{
+			JainIndex:                    res.Fairness.JainIndex,
+			MaxShareDeviation:            res.Fairness.MaxShareDeviation,
+			LongestStarvationSeconds:     res.Fairness.LongestStarvation.Seconds(),
+			LongestStarvationFairnessKey: res.Fairness.LongestStarvationFairnessKey,
+		},
+		ByPriority:    make(map[string]priorityDocument),
+		ByFairnessKey: make(map[string]fairnessKeyDocument),
+	}
+	for p, count := range res.CountByPriority {
+		doc.ByPriority[p.String()] = priorityDocument{
+			Count:     count,
+			Boosted:   res.BoostedByPriority[p],
+			Queued:    newQueuedDocument(res.QueuedAvgByPriority[p], res.QueuedP50ByPriority[p], res.QueuedP95ByPriority[p], res.QueuedP99ByPriority[p]),
+			Latency:   newLatenciesDocument(res.LatenciesByPriority[p]),
+			Deadlines: newDeadlineDocument(res.DeadlinesByPriority[p]),
+		}
+	}
+	for _, key := range fairnessKeysOf(res) {
+		keyDoc := fairnessKeyDocument{
+			Count:                    res.CountByFairnessKey[key],
+			Queued:                   newQueuedDocument(res.QueuedAvgByFairnessKey[key], res.QueuedP50ByFairnessKey[key], res.QueuedP95ByFairnessKey[key], res.QueuedP99ByFairnessKey[key]),
+			Latency:                  newLatenciesDocument(res.LatenciesByFairnessKey[key]),
+			WorkerTimeSeconds:        res.WorkerTimeByFairnessKey[key].Seconds(),
+			Share:                    res.Fairness.ShareByFairnessKey[key],
+			ConfiguredShare:          res.Fairness.ConfiguredShareByFairnessKey[key],
+			LongestStarvationSeconds: res.Fairness.LongestStarvationByFairnessKey[key].Seconds(),
+			Deadlines:                newDeadlineDocument(res.DeadlinesByFairnessKey[key]),
+		}
+		if res.ConcurrencyMaxByFairnessKey != nil {
+			concurrencyMax, concurrencyAvg := res.ConcurrencyMaxByFairnessKey[key], res.ConcurrencyAvgByFairnessKey[key]
+			keyDoc.ConcurrencyMax = &concurrencyMax
+			keyDoc.ConcurrencyAvg = &concurrencyAvg
+		}
+		doc.ByFairnessKey[key] = keyDoc
+	}
+	for path, latencies := range res.LatenciesByFairnessPath {
+		if doc.ByFairnessPath == nil {
+			doc.ByFairnessPath = make(map[string]fairnessPathDocument)
+		}
+		doc.ByFairnessPath[path] = fairnessPathDocument{
+			Count:             res.CountByFairnessPath[path],
+			Latency:           newLatenciesDocument(latencies),
+			WorkerTimeSeconds: res.WorkerTimeByFairnessPath[path].Seconds(),
+			Share:             res.Fairness.ShareByFairnessPath[path],
+		}
+	}
+	return doc
+}
+
+// WriteRunsJSON writes the summary of the metrics across runs as a json document.
+func WriteRunsJSON(w io.Writer, runs Runs) error {
+	doc := runsDocument{
+		SchemaVersion: ResultsSchemaVersion,
+		Runs:          len(runs.Results),
+		Seeds:         runs.Seeds,
+	}
+	for _, m := range runs.Metrics() {
+		doc.Metrics = append(doc.Metrics, metricSummaryDocument{
+			Scope:        m.Scope,
+			ScopeKey:     m.ScopeKey,
+			Dimension:    m.Dimension,
+			DimensionKey: m.DimensionKey,
+			Name:         m.Name,
+			Runs:         m.Runs,
+			Mean:         m.Mean,
+			StdDev:       m.StdDev,
+			CI95Low:      m.Mean - m.CI95,
+			CI95High:     m.Mean + m.CI95,
+		})
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(doc)
+}
+
+// WriteRunsCSV writes the summary of the metrics across runs as csv in the long
+// format of [WriteResultsCSV], with the value replaced by summary statistics.
+func WriteRunsCSV(w io.Writer, runs Runs) error {
+	cw := csv.NewWriter(w)
+	_ = cw.Write([]string{"schema_version", "scope", "scope_key", "dimension", "dimension_key", "metric", "runs", "mean", "std_dev", "ci95_low", "ci95_high"})
+	schemaVersion := strconv.Itoa(ResultsSchemaVersion)
+	formatFloat := func(value float64) string { return strconv.FormatFloat(value, 'f', -1, 64) }
+	for _, m := range runs.Metrics() {
+		_ = cw.Write([]string{
+			schemaVersion,
+			m.Scope,
+			m.ScopeKey,
+			m.Dimension,
+			m.DimensionKey,
+			m.Name,
+			strconv.Itoa(m.Runs),
+			formatFloat(m.Mean),
+			formatFloat(m.StdDev),
+			formatFloat(m.Mean - m.CI95),
+			formatFloat(m.Mean + m.CI95),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+type runsDocument struct {
+	SchemaVersion int                     `json:"schemaVersion"`
+	Runs          int                     `json:"runs"`
+	Seeds         []uint64                `json:"seeds"`
+	Metrics       []metricSummaryDocument `json:"metrics"`
+}
+
+type metricSummaryDocument struct {
+	Scope        string  `json:"scope"`
+	ScopeKey     string  `json:"scopeKey,omitempty"`
+	Dimension    string  `json:"dimension"`
+	DimensionKey string  `json:"dimensionKey,omitempty"`
+	Name         string  `json:"metric"`
+	Runs         int     `json:"runs"`
+	Mean         float64 `json:"mean"`
+	StdDev       float64 `json:"stdDev"`
+	CI95Low      float64 `json:"ci95Low"`
+	CI95High     float64 `json:"ci95High"`
+}
+
+// WriteSweepJSON writes the results of a parameter sweep as a json document with
+// the queueing delay of each point of the grid, overall and by fairness key.
+func WriteSweepJSON(w io.Writer, sweep SweepResults) error {
+	doc := sweepDocument{SchemaVersion: ResultsSchemaVersion}
+	for _, param := range sweep.Parameters {
+		doc.Parameters = append(doc.Parameters, param.Name)
+	}
+	for _, point := range sweep.Points {
+		pointDoc := sweepPointDocument{
+			Parameters:     make(map[string]string, len(point.Values)),
+			TasksProcessed: point.Results.TasksProcessed,
+			Queued:         newQueuedDocument(point.Results.QueuedAvg, point.Results.QueuedP50, point.Results.QueuedP95, point.Results.QueuedP99),
+			ByFairnessKey:  make(map[string]sweepFairnessKeyDocument),
+		}
+		for index, value := range point.Values {
+			pointDoc.Parameters[sweep.Parameters[index].Name] = value
+		}
+		res := point.Results
+		for _, key := range fairnessKeysOf(res) {
+			pointDoc.ByFairnessKey[key] = sweepFairnessKeyDocument{
+				Count:  res.CountByFairnessKey[key],
+				Queued: newQueuedDocument(res.QueuedAvgByFairnessKey[key], res.QueuedP50ByFairnessKey[key], res.QueuedP95ByFairnessKey[key], res.QueuedP99ByFairnessKey[key]),
+			}
+		}
+		doc.Points = append(doc.Points, pointDoc)
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(doc)
+}
+
+// WriteSweepCSV writes the results of a parameter sweep as csv with a row per point of the
+// grid and dimension, and a column per swept parameter.
+func WriteSweepCSV(w io.Writer, sweep SweepResults) error {
+	cw := csv.NewWriter(w)
+	header := []string{"schema_version"}
+	for _, param := range sweep.Parameters {
+		header = append(header, param.Name)
+	}
+	_ = cw.Write(append(header, "dimension", "dimension_key", "count", "queued_avg_seconds", "queued_p50_seconds", "queued_p95_seconds", "queued_p99_seconds"))
+	schemaVersion := strconv.Itoa(ResultsSchemaVersion)
+	for _, point := range sweep.Points {
+		res := point.Results
+		write := func(dimension, dimensionKey string, count int, avg, p50, p95, p99 time.Duration) {
+			row := append([]string{schemaVersion}, point.Values...)
+			_ = cw.Write(append(row,
+				dimension,
+				dimensionKey,
+				strconv.Itoa(count),
+				strconv.FormatFloat(avg.Seconds(), 'f', -1, 64),
+				strconv.FormatFloat(p50.Seconds(), 'f', -1, 64),
+				strconv.FormatFloat(p95.Seconds(), 'f', -1, 64),
+				strconv.FormatFloat(p99.Seconds(), 'f', -1, 64),
+			))
+		}
+		write("all", "", res.TasksProcessed, res.QueuedAvg, res.QueuedP50, res.QueuedP95, res.QueuedP99)
+		for _, key := range fairnessKeysOf(res) {
+			write("fairness_key", key, res.CountByFairnessKey[key], res.QueuedAvgByFairnessKey[key], res.QueuedP50ByFairnessKey[key], res.QueuedP95ByFairnessKey[key], res.QueuedP99ByFairnessKey[key])
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+type sweepDocument struct {
+	SchemaVersion int                  `json:"schemaVersion"`
+	Parameters    []string             `json:"parameters"`
+	Points        []sweepPointDocument `json:"points"`
+}
+
+type sweepPointDocument struct {
+	Parameters     map[string]string                   `json:"parameters"`
+	TasksProcessed int                                 `json:"tasksProcessed"`
+	Queued         queuedDocument                      `json:"queued"`
+	ByFairnessKey  map[string]sweepFairnessKeyDocument `json:"byFairnessKey"`
+}
+
+type sweepFairnessKeyDocument struct {
+	Count  int            `json:"count"`
+	Queued queuedDocument `json:"queued"`
+}
@@ -0,0 +1,91 @@
+package sim
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func testResults() SimulationResults {
+	return SimulationResults{
+		TasksProcessed:         3,
+		ElapsedTime:            time.Minute,
+		CountByPriority:        map[Priority]int{P2: 3},
+		CountByFairnessKey:     map[string]int{"a": 3},
+		QueuedAvg:              2 * time.Second,
+		QueuedAvgByPriority:    map[Priority]time.Duration{P2: 2 * time.Second},
+		QueuedAvgByFairnessKey: map[string]time.Duration{"a": 2 * time.Second},
+		Buckets: []BucketResults{
+			{Start: 0, End: time.Minute, QueueLength: 5, Throughput: 0.05, Results: SimulationResults{TasksProcessed: 3}},
+		},
+	}
+}
+
+func Test_WriteResultsJSON(t *testing.T) {
+	buffer := new(bytes.Buffer)
+	if err := WriteResultsJSON(buffer, testResults()); err != nil {
+		t.Errorf("expect write error to be nil, was %v", err)
+		t.FailNow()
+	}
+
+	var doc struct {
+		SchemaVersion int `json:"schemaVersion"`
+		ByPriority    map[string]struct {
+			Count  int `json:"count"`
+			Queued struct {
+				AvgSeconds float64 `json:"avgSeconds"`
+			} `json:"queued"`
+		} `json:"byPriority"`
+		Buckets []struct {
+			EndSeconds  float64 `json:"endSeconds"`
+			QueueLength int     `json:"queueLength"`
+		} `json:"buckets"`
+	}
+	if err := json.Unmarshal(buffer.Bytes(), &doc); err != nil {
+		t.Errorf("expect unmarshal error to be nil, was %v", err)
+		t.FailNow()
+	}
+	if doc.SchemaVersion != ResultsSchemaVersion {
+		t.Errorf("expect schema version to be %d, was %d", ResultsSchemaVersion, doc.SchemaVersion)
+		t.Fail()
+	}
+	if doc.ByPriority["P2"].Count != 3 || doc.ByPriority["P2"].Queued.AvgSeconds != 2 {
+		t.Errorf("expect priorities to be keyed by name with durations in seconds, was %+v", doc.ByPriority)
+		t.Fail()
+	}
+	if len(doc.Buckets) != 1 || doc.Buckets[0].EndSeconds != 60 || doc.Buckets[0].QueueLength != 5 {
+		t.Errorf("expect one bucket ending at 60s with a queue length of 5, was %+v", doc.Buckets)
+		t.Fail()
+	}
+}
+
+func Test_WriteResultsCSV(t *testing.T) {
+	buffer := new(bytes.Buffer)
+	if err := WriteResultsCSV(buffer, testResults()); err != nil {
+		t.Errorf("expect write error to be nil, was %v", err)
+		t.FailNow()
+	}
+	records, err := csv.NewReader(buffer).ReadAll()
+	if err != nil {
+		t.Errorf("expect read error to be nil, was %v", err)
+		t.FailNow()
+	}
+	if len(records) < 2 || records[0][0] != "schema_version" || records[0][6] != "value" {
+		t.Errorf("expect a header row, was %v", records)
+		t.FailNow()
+	}
+	found := make(map[string]string)
+	for _, record := range records[1:] {
+		found[record[1]+"/"+record[2]+"/"+record[3]+"/"+record[4]+"/"+record[5]] = record[6]
+	}
+	if value := found["run//priority/P2/queued_avg_seconds"]; value != "2" {
+		t.Errorf("expect the P2 queued avg to be 2 seconds, was %q", value)
+		t.Fail()
+	}
+	if value := found["bucket/60/all//queue_length"]; value != "5" {
+		t.Errorf("expect the bucket queue length to be 5, was %q", value)
+		t.Fail()
+	}
+}
@@ -0,0 +1,168 @@
+package sim
+
+import (
+	"math"
+	"math/rand/v2"
+	"runtime"
+	"sync"
+)
+
+// Runs are the results of independent runs of a scenario.
+type Runs struct {
+	Seeds   []uint64
+	Results []SimulationResults
+}
+
+// RunMany runs a scenario a given number of times, in parallel across at most the given number
+// of goroutines (or GOMAXPROCS if not positive), and returns the results in run order.
+//
+// Run n is seeded with the scenario's seed (or a random seed if unset) plus n, such that the
+// runs are independent of each other but reproducible as a whole. Progress logging of the runs
+// is interleaved, and so is typically discarded by setting [LogOutput] to [io.Discard].
+func RunMany(sc Scenario, runs, parallelism int) (output Runs, err error) {
+	if err = sc.Validate(); err != nil {
+		return
+	}
+	seed := rand.Uint64()
+	if sc.Seed != nil {
+		seed = *sc.Seed
+	}
+	output.Seeds = make([]uint64, runs)
+	output.Results = make([]SimulationResults, runs)
+	errs := make([]error, runs)
+
+	parallelFor(runs, parallelism, func(index int) {
+		run := sc
+		runSeed := seed + uint64(index)
+		run.Seed = &runSeed
+		output.Seeds[index] = runSeed
+		s, err := run.NewSimulation(nil)
+		if err != nil {
+			errs[index] = err
+			return
+		}
+		output.Results[index] = s.Simulate()
+	})
+
+	for _, err = range errs {
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+// parallelFor calls a function with each index from 0 to n, from at most the
+// given number of goroutines (or GOMAXPROCS if not positive) at once.
+func parallelFor(n, parallelism int, fn func(index int)) {
+	if parallelism <= 0 {
+		parallelism = runtime.GOMAXPROCS(0)
+	}
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for range min(parallelism, n) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range indexes {
+				fn(index)
+			}
+		}()
+	}
+	for index := range n {
+		indexes <- index
+	}
+	close(indexes)
+	wg.Wait()
+}
+
+// MetricSummary is the mean of a metric across runs with its 95% confidence interval.
+type MetricSummary struct {
+	Scope        string
+	ScopeKey     string
+	Dimension    string
+	DimensionKey string
+	Name         string
+	// Runs is the number of runs the metric appears in, e.g. a fairness key
+	// that only has arrivals in some of the runs.
+	Runs   int
+	Mean   float64
+	StdDev float64
+	// CI95 is the half width of the 95% confidence interval of the mean, or
+	// zero if the metric appears in fewer than two runs.
+	CI95 float64
+}
+
+// Metrics returns the summary of each metric of [SimulationResults.Metrics] across the runs,
+// in the order they first appear.
+func (runs Runs) Metrics() (output []MetricSummary) {
+	type metricKey struct {
+		scope, scopeKey, dimension, dimensionKey, name string
+	}
+	var keys []metricKey
+	valuesByKey := make(map[metricKey][]float64)
+	for _, res := range runs.Results {
+		for _, m := range res.Metrics() {
+			key := metricKey{m.Scope, m.ScopeKey, m.Dimension, m.DimensionKey, m.Name}
+			if _, ok := valuesByKey[key]; !ok {
+				keys = append(keys, key)
+			}
+			valuesByKey[key] = append(valuesByKey[key], m.Value)
+		}
+	}
+	for _, key := range keys {
+		values := valuesByKey[key]
+		summary := MetricSummary{
+			Scope:        key.scope,
+			ScopeKey:     key.scopeKey,
+			Dimension:    key.dimension,
+			DimensionKey: key.dimensionKey,
+			Name:         key.name,
+			Runs:         len(values),
+		}
+		summary.Mean, summary.StdDev = meanAndStdDev(values)
+		if len(values) > 1 {
+			summary.CI95 = tQuantile975(len(values)-1) * summary.StdDev / math.Sqrt(float64(len(values)))
+		}
+		output = append(output, summary)
+	}
+	return
+}
+
+// meanAndStdDev returns the mean and the sample standard deviation of the values.
+func meanAndStdDev(values []float64) (mean, stdDev float64) {
+	if len(values) == 0 {
+		return
+	}
+	for _, v := range values {
+		mean += v
+	}
+	mean /= float64(len(values))
+	if len(values) < 2 {
+		return
+	}
+	var sumOfSquares float64
+	for _, v := range values {
+		sumOfSquares += (v - mean) * (v - mean)
+	}
+	stdDev = math.Sqrt(sumOfSquares / float64(len(values)-1))
+	return
+}
+
+// tQuantiles975 are the 97.5th percentiles of the student's t-distribution for 1 to 30 degrees of freedom.
+var tQuantiles975 = []float64{
+	12.706, 4.303, 3.182, 2.776, 2.571, 2.447, 2.365, 2.306, 2.262, 2.228,
+	2.201, 2.179, 2.160, 2.145, 2.131, 2.120, 2.110, 2.101, 2.093, 2.086,
+	2.080, 2.074, 2.069, 2.064, 2.060, 2.056, 2.052, 2.048, 2.045, 2.042,
+}
+
+// tQuantile975 returns the 97.5th percentile of the student's t-distribution, i.e. the
+// multiple of the standard error for a two sided 95% confidence interval.
+func tQuantile975(degreesOfFreedom int) float64 {
+	if degreesOfFreedom <= len(tQuantiles975) {
+		return tQuantiles975[max(degreesOfFreedom, 1)-1]
+	}
+	// past 30 the first order expansion around the normal distribution is within 0.2%.
+	const z = 1.959964
+	return z + (z*z*z+z)/(4*float64(degreesOfFreedom))
+}
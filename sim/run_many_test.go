@@ -0,0 +1,64 @@
+package sim
+
+import (
+	"math"
+	"testing"
+)
+
+func Test_RunMany(t *testing.T) {
+	seed := uint64(100)
+	scenario := Scenario{
+		Seed:           &seed,
+		Engine:         "event",
+		Duration:       "1m",
+		WorkerCount:    2,
+		TasksPerSecond: 200,
+		Arrivals:       &ArrivalSpec{Type: "poisson", TasksPerSecond: 200},
+		Queue:          QueueSpec{Type: "fairness"},
+	}
+	runs, err := RunMany(scenario, 4, 2)
+	if err != nil {
+		t.Errorf("expect run many error to be nil, was %v", err)
+		t.FailNow()
+	}
+	if len(runs.Results) != 4 || runs.Seeds[0] != 100 || runs.Seeds[3] != 103 {
+		t.Errorf("expect four runs seeded 100 to 103, was %v", runs.Seeds)
+		t.FailNow()
+	}
+
+	// each run is the same as running its seed on its own.
+	runSeed := uint64(102)
+	scenario.Seed = &runSeed
+	s, _ := scenario.NewSimulation(nil)
+	if res := s.Simulate(); res.TasksProcessed != runs.Results[2].TasksProcessed {
+		t.Errorf("expect the third run to match a run seeded 102, was %d vs. %d tasks", runs.Results[2].TasksProcessed, res.TasksProcessed)
+		t.Fail()
+	}
+
+	for _, m := range runs.Metrics() {
+		if m.Scope != "run" || m.Name != "tasks_processed" {
+			continue
+		}
+		if m.Runs != 4 || m.CI95 <= 0 {
+			t.Errorf("expect tasks processed to vary across the four runs, was %+v", m)
+			t.Fail()
+		}
+		// ~12,000 poisson arrivals vary by ~110 between runs.
+		if math.Abs(m.Mean-12_000) > 500 || m.CI95 > 1_000 {
+			t.Errorf("expect tasks processed to be ~12000 with a narrow interval, was %.0f ± %.0f", m.Mean, m.CI95)
+			t.Fail()
+		}
+	}
+}
+
+func Test_meanAndStdDev(t *testing.T) {
+	mean, stdDev := meanAndStdDev([]float64{2, 4, 4, 4, 5, 5, 7, 9})
+	if mean != 5 || math.Abs(stdDev-2.138) > 0.001 {
+		t.Errorf("expect mean 5 and sample std dev 2.138, was %v and %v", mean, stdDev)
+		t.Fail()
+	}
+	if tQuantile975(1) != 12.706 || math.Abs(tQuantile975(60)-2.000) > 0.002 {
+		t.Errorf("expect t quantiles to be 12.706 at 1 and ~2.000 at 60 degrees of freedom, was %v and %v", tQuantile975(1), tQuantile975(60))
+		t.Fail()
+	}
+}
@@ -0,0 +1,440 @@
+package sim
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Scenario is a complete, serializable description of a simulation run, i.e.
+// the simulation config, the task queue and its parameters, the tenants and the seed.
+//
+// Unset fields fall back to the same defaults as [SimulationConfig], and
+// durations are duration strings, e.g. "15m".
+type Scenario struct {
+	// Seed seeds the simulation's random source; if unset, a random seed is used.
+	Seed *uint64 `json:"seed,omitempty"`
+
+	// Engine is one of "tick" or "event".
+	Engine                   string `json:"engine,omitempty"`
+	Duration                 string `json:"duration,omitempty"`
+	TickInterval             string `json:"tickInterval,omitempty"`
+	ResultsBucketingInterval string `json:"resultsBucketingInterval,omitempty"`
+
+	WorkerCount     int `json:"workerCount,omitempty"`
+	WorkerTaskSlots int `json:"workerTaskSlots,omitempty"`
+	TasksPerSecond  int `json:"tasksPerSecond,omitempty"`
+
+	// Percentiles are the percentiles latencies are reported at, e.g. [50, 99, 99.9].
+	Percentiles []float64 `json:"percentiles,omitempty"`
+
+	Arrivals               *ArrivalSpec                `json:"arrivals,omitempty"`
+	TaskDuration           *DistributionSpec           `json:"taskDuration,omitempty"`
+	TaskDurationByPriority map[string]DistributionSpec `json:"taskDurationByPriority,omitempty"`
+	PriorityWeights        map[string]int              `json:"priorityWeights,omitempty"`
+	// Deadlines are how soon after it is created a task of each priority (e.g. "P1")
+	// should be dispatched, as duration strings, e.g. {"P1": "30s"}.
+	Deadlines map[string]string `json:"deadlines,omitempty"`
+
+	Tenants []TenantSpec `json:"tenants,omitempty"`
+	Phases  []PhaseSpec  `json:"phases,omitempty"`
+	Queue   QueueSpec    `json:"queue"`
+}
+
+// TenantSpec is the serializable form of a [TenantProfile].
+type TenantSpec struct {
+	FairnessKey     string            `json:"fairnessKey"`
+	Fairness        float64           `json:"fairness"`
+	ArrivalWeight   int               `json:"arrivalWeight,omitempty"`
+	Arrivals        *ArrivalSpec      `json:"arrivals,omitempty"`
+	PriorityWeights map[string]int    `json:"priorityWeights,omitempty"`
+	TaskDuration    *DistributionSpec `json:"taskDuration,omitempty"`
+}
+
+// PhaseSpec is the serializable form of a [Phase].
+type PhaseSpec struct {
+	Name               string               `json:"name"`
+	Start              string               `json:"start"`
+	Arrivals           *ArrivalSpec         `json:"arrivals,omitempty"`
+	Tenants            []TenantSpec         `json:"tenants,omitempty"`
+	ArrivalMultipliers map[string]float64   `json:"arrivalMultipliers,omitempty"`
+	WorkerCount        int                  `json:"workerCount,omitempty"`
+	ConcurrencyLimit   int                  `json:"concurrencyLimit,omitempty"`
+	ConcurrencyLimits  map[string]int       `json:"concurrencyLimits,omitempty"`
+	RateLimits         map[string]LimitSpec `json:"rateLimits,omitempty"`
+}
+
+// QueueSpec is the serializable form of [TaskQueueOptions].
+type QueueSpec struct {
+	// Type is one of [TaskQueueTypes].
+	Type string `json:"type"`
+	// CostModel is one of "count" or "worker-time".
+	CostModel         string               `json:"costModel,omitempty"`
+	RateLimits        map[string]LimitSpec `json:"rateLimits,omitempty"`
+	ConcurrencyLimit  int                  `json:"concurrencyLimit,omitempty"`
+	ConcurrencyLimits map[string]int       `json:"concurrencyLimits,omitempty"`
+	// Aging raises the priority of tasks as they wait, for the "priority" and "fairness" queue types.
+	Aging *AgingSpec `json:"aging,omitempty"`
+	// PriorityClassWeights are the weights by priority (e.g. "P0") that the "fairness" queue type
+	// shares dispatches between priorities by, rather than serving them strictly by priority.
+	PriorityClassWeights map[string]int `json:"priorityClassWeights,omitempty"`
+	// PathWeights are the weights of the paths of hierarchical fairness keys (e.g. "acct1" or
+	// "acct1/merchantA") for the "hfq" queue type; paths default to the fairness weight of their
+	// tenant, or 1.
+	PathWeights map[string]float64 `json:"pathWeights,omitempty"`
+}
+
+// LoadScenario reads a scenario from a json file.
+func LoadScenario(path string) (Scenario, error) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return Scenario{}, err
+	}
+	scenario, err := ParseScenario(contents)
+	if err != nil {
+		return Scenario{}, fmt.Errorf("%s: %w", path, err)
+	}
+	return scenario, nil
+}
+
+// ParseScenario parses and validates a scenario from json.
+//
+// Unknown fields are rejected, and errors refer to the offending field or position.
+func ParseScenario(contents []byte) (scenario Scenario, err error) {
+	decoder := json.NewDecoder(bytes.NewReader(contents))
+	decoder.DisallowUnknownFields()
+	if err = decoder.Decode(&scenario); err != nil {
+		err = describeJSONError(contents, err)
+		return
+	}
+	if _, err = decoder.Token(); err != io.EOF {
+		err = errors.New("unexpected content after the scenario object")
+		return
+	}
+	err = scenario.Validate()
+	return
+}
+
+// Validate returns an error for each invalid field of the scenario.
+func (sc Scenario) Validate() error {
+	_, _, err := sc.build()
+	return err
+}
+
+// SimulationConfig returns the simulation config for the scenario.
+func (sc Scenario) SimulationConfig() (SimulationConfig, error) {
+	cfg, _, err := sc.build()
+	return cfg, err
+}
+
+// TaskQueueOptions returns the task queue options for the scenario.
+func (sc Scenario) TaskQueueOptions() (TaskQueueOptions, error) {
+	_, opts, err := sc.build()
+	return opts, err
+}
+
+// NewSimulation returns a new, initialized simulation for the scenario.
+//
+// Each call returns a simulation with its own (fresh) arrival processes
+// and task queue, such that scenarios can be run repeatedly.
+func (sc Scenario) NewSimulation(c Clock) (*Simulation, error) {
+	cfg, opts, err := sc.build()
+	if err != nil {
+		return nil, err
+	}
+	if c == nil {
+		c = NewSimulatedClock(SimulatedStartTime)
+	}
+	s := &Simulation{
+		Config: cfg,
+		Clock:  c,
+	}
+	seed := rand.Uint64()
+	if sc.Seed != nil {
+		seed = *sc.Seed
+	}
+	s.RandSource = rand.NewPCG(seed, seed)
+	// the task queue draws from its own stream such that the arrivals (and their priorities
+	// and durations) are the same for a given seed regardless of the task queue type. It is
+	// built ahead of Init, which records its limits as the base for the phases.
+	s.TaskQueue, err = NewTaskQueue(opts, rand.New(rand.NewPCG(seed, ^seed)), s.Clock)
+	if err != nil {
+		return nil, err
+	}
+	if err = s.Init(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (sc Scenario) build() (cfg SimulationConfig, opts TaskQueueOptions, err error) {
+	var errs fieldErrors
+
+	engine, engineErr := ParseEngine(sc.Engine)
+	if engineErr != nil {
+		errs.add("engine", "must be one of tick or event, was %q", sc.Engine)
+	}
+	cfg.Engine = engine
+	cfg.Duration = errs.duration("duration", sc.Duration)
+	cfg.TickInterval = errs.duration("tickInterval", sc.TickInterval)
+	cfg.ResultsBucketingInterval = errs.duration("resultsBucketingInterval", sc.ResultsBucketingInterval)
+	errs.nonNegative("duration", float64(cfg.Duration))
+	errs.nonNegative("tickInterval", float64(cfg.TickInterval))
+	errs.nonNegative("resultsBucketingInterval", float64(cfg.ResultsBucketingInterval))
+	errs.nonNegative("workerCount", float64(sc.WorkerCount))
+	errs.nonNegative("workerTaskSlots", float64(sc.WorkerTaskSlots))
+	errs.nonNegative("tasksPerSecond", float64(sc.TasksPerSecond))
+	cfg.WorkerCount = sc.WorkerCount
+	cfg.WorkerTaskSlots = sc.WorkerTaskSlots
+	cfg.TasksPerSecond = sc.TasksPerSecond
+	for index, percentile := range sc.Percentiles {
+		if percentile <= 0 || percentile > 100 {
+			errs.add(fmt.Sprintf("percentiles[%d]", index), "must be greater than 0 and at most 100, was %v", percentile)
+		}
+	}
+	cfg.Percentiles = sc.Percentiles
+
+	if sc.Arrivals != nil {
+		cfg.ArrivalProcess, err = sc.Arrivals.ArrivalProcess("arrivals")
+		errs.join(err)
+	}
+	if sc.TaskDuration != nil {
+		cfg.TaskDuration, err = sc.TaskDuration.DurationDistribution("taskDuration")
+		errs.join(err)
+	}
+	if len(sc.TaskDurationByPriority) > 0 {
+		cfg.TaskDurationByPriority = make(map[Priority]DurationDistribution)
+		for _, key := range sortedMapKeys(sc.TaskDurationByPriority) {
+			path := "taskDurationByPriority." + key
+			p, parseErr := ParsePriority(key)
+			if parseErr != nil {
+				errs.add(path, "must be a priority (P0-P4)")
+				continue
+			}
+			cfg.TaskDurationByPriority[p], err = sc.TaskDurationByPriority[key].DurationDistribution(path)
+			errs.join(err)
+		}
+	}
+	cfg.PriorityWeights = errs.priorityWeights("priorityWeights", sc.PriorityWeights)
+	if len(sc.Deadlines) > 0 {
+		cfg.DeadlineByPriority = make(map[Priority]time.Duration)
+		for _, key := range sortedMapKeys(sc.Deadlines) {
+			path := "deadlines." + key
+			p, parseErr := ParsePriority(key)
+			if parseErr != nil {
+				errs.add(path, "must be a priority (P0-P4)")
+				continue
+			}
+			cfg.DeadlineByPriority[p] = errs.duration(path, sc.Deadlines[key])
+			errs.positive(path, float64(cfg.DeadlineByPriority[p]))
+		}
+	}
+
+	cfg.Tenants = errs.tenants("tenants", sc.Tenants)
+
+	opts.Type = sc.Queue.Type
+	if !slices.Contains(TaskQueueTypes, sc.Queue.Type) {
+		errs.add("queue.type", "must be one of %s, was %q", strings.Join(TaskQueueTypes, ", "), sc.Queue.Type)
+	}
+	costModel, costModelErr := ParseCostModel(sc.Queue.CostModel)
+	if costModelErr != nil {
+		errs.add("queue.costModel", "must be one of count or worker-time, was %q", sc.Queue.CostModel)
+	}
+	opts.CostModel = costModel
+	if len(sc.Queue.RateLimits) > 0 && sc.Queue.Type != "feeder" {
+		errs.add("queue.rateLimits", "is only supported by the feeder queue type")
+	}
+	opts.RateLimits = errs.rateLimits("queue.rateLimits", sc.Queue.RateLimits)
+	errs.nonNegative("queue.concurrencyLimit", float64(sc.Queue.ConcurrencyLimit))
+	opts.ConcurrencyLimit = sc.Queue.ConcurrencyLimit
+	for _, key := range sortedMapKeys(sc.Queue.ConcurrencyLimits) {
+		errs.positive("queue.concurrencyLimits."+key, float64(sc.Queue.ConcurrencyLimits[key]))
+	}
+	opts.ConcurrencyLimits = sc.Queue.ConcurrencyLimits
+	if sc.Queue.Aging != nil && sc.Queue.Type != "priority" && sc.Queue.Type != "fairness" {
+		errs.add("queue.aging", "is only supported by the priority and fairness queue types")
+	}
+	opts.Aging, err = sc.Queue.Aging.AgingPolicy("queue.aging")
+	errs.join(err)
+	if len(sc.Queue.PriorityClassWeights) > 0 && sc.Queue.Type != "fairness" {
+		errs.add("queue.priorityClassWeights", "is only supported by the fairness queue type")
+	}
+	opts.PriorityClassWeights = errs.priorityWeights("queue.priorityClassWeights", sc.Queue.PriorityClassWeights)
+	if len(sc.Queue.PathWeights) > 0 && sc.Queue.Type != "hfq" {
+		errs.add("queue.pathWeights", "is only supported by the hfq queue type")
+	}
+	for _, path := range sortedMapKeys(sc.Queue.PathWeights) {
+		errs.positive("queue.pathWeights."+path, sc.Queue.PathWeights[path])
+	}
+	opts.PathWeights = sc.Queue.PathWeights
+
+	var lastStart time.Duration
+	for index, phase := range sc.Phases {
+		path := fmt.Sprintf("phases[%d]", index)
+		p := Phase{
+			Name:               phase.Name,
+			Start:              errs.duration(path+".start", phase.Start),
+			Tenants:            errs.tenants(path+".tenants", phase.Tenants),
+			ArrivalMultipliers: phase.ArrivalMultipliers,
+			WorkerCount:        phase.WorkerCount,
+			ConcurrencyLimit:   phase.ConcurrencyLimit,
+			ConcurrencyLimits:  phase.ConcurrencyLimits,
+			RateLimits:         errs.rateLimits(path+".rateLimits", phase.RateLimits),
+		}
+		if index > 0 && p.Start <= lastStart {
+			errs.add(path+".start", "must be after the start of the previous phase")
+		}
+		if p.Start >= cfg.DurationOrDefault() {
+			errs.add(path+".start", "must be before the end of the simulation (%v)", cfg.DurationOrDefault())
+		}
+		lastStart = p.Start
+		if phase.Arrivals != nil {
+			p.ArrivalProcess, err = phase.Arrivals.ArrivalProcess(path + ".arrivals")
+			errs.join(err)
+		}
+		for _, key := range sortedMapKeys(phase.ArrivalMultipliers) {
+			errs.nonNegative(path+".arrivalMultipliers."+key, phase.ArrivalMultipliers[key])
+		}
+		errs.nonNegative(path+".workerCount", float64(phase.WorkerCount))
+		errs.nonNegative(path+".concurrencyLimit", float64(phase.ConcurrencyLimit))
+		for _, key := range sortedMapKeys(phase.ConcurrencyLimits) {
+			errs.positive(path+".concurrencyLimits."+key, float64(phase.ConcurrencyLimits[key]))
+		}
+		if len(phase.RateLimits) > 0 && sc.Queue.Type != "feeder" {
+			errs.add(path+".rateLimits", "is only supported by the feeder queue type")
+		}
+		cfg.Phases = append(cfg.Phases, p)
+	}
+
+	err = errs.err()
+	return
+}
+
+func (fe *fieldErrors) tenants(path string, tenants []TenantSpec) (output []TenantProfile) {
+	fairnessKeys := make(map[string]int)
+	for index, tenant := range tenants {
+		tenantPath := fmt.Sprintf("%s[%d]", path, index)
+		if previous, ok := fairnessKeys[tenant.FairnessKey]; ok {
+			fe.add(tenantPath+".fairnessKey", "duplicates %s[%d].fairnessKey %q", path, previous, tenant.FairnessKey)
+		}
+		fairnessKeys[tenant.FairnessKey] = index
+		fe.nonNegative(tenantPath+".fairness", tenant.Fairness)
+		fe.nonNegative(tenantPath+".arrivalWeight", float64(tenant.ArrivalWeight))
+		profile := TenantProfile{
+			FairnessKey:     tenant.FairnessKey,
+			Fairness:        tenant.Fairness,
+			ArrivalWeight:   tenant.ArrivalWeight,
+			PriorityWeights: fe.priorityWeights(tenantPath+".priorityWeights", tenant.PriorityWeights),
+		}
+		if tenant.Arrivals == nil && tenant.ArrivalWeight == 0 {
+			fe.add(tenantPath, "must have either an arrivalWeight or arrivals")
+		}
+		var err error
+		if tenant.Arrivals != nil {
+			profile.Arrivals, err = tenant.Arrivals.ArrivalProcess(tenantPath + ".arrivals")
+			fe.join(err)
+		}
+		if tenant.TaskDuration != nil {
+			profile.TaskDuration, err = tenant.TaskDuration.DurationDistribution(tenantPath + ".taskDuration")
+			fe.join(err)
+		}
+		output = append(output, profile)
+	}
+	return
+}
+
+func (fe *fieldErrors) rateLimits(path string, limits map[string]LimitSpec) map[string]Limit {
+	if len(limits) == 0 {
+		return nil
+	}
+	output := make(map[string]Limit, len(limits))
+	for _, key := range sortedMapKeys(limits) {
+		limit := limits[key]
+		limitPath := path + "." + key
+		quantum := fe.duration(limitPath+".quantum", limit.Quantum)
+		fe.positive(limitPath+".actions", float64(limit.Actions))
+		fe.positive(limitPath+".quantum", float64(quantum))
+		output[key] = Limit{Actions: limit.Actions, Quantum: quantum}
+	}
+	return output
+}
+
+func (fe *fieldErrors) priorityWeights(path string, weights map[string]int) map[Priority]int {
+	if len(weights) == 0 {
+		return nil
+	}
+	output := make(map[Priority]int, len(weights))
+	for _, key := range sortedMapKeys(weights) {
+		p, err := ParsePriority(key)
+		if err != nil {
+			fe.add(path+"."+key, "must be a priority (P0-P4)")
+			continue
+		}
+		fe.nonNegative(path+"."+key, float64(weights[key]))
+		output[p] = weights[key]
+	}
+	return output
+}
+
+// describeJSONError adds the field or line and column to json decoding errors.
+func describeJSONError(contents []byte, err error) error {
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		return FieldError{Field: jsonFieldPath(typeErr.Field), Message: fmt.Sprintf("cannot use a json %s as %v", typeErr.Value, typeErr.Type)}
+	}
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		line, column := lineAndColumn(contents, syntaxErr.Offset)
+		return fmt.Errorf("line %d, column %d: %w", line, column, err)
+	}
+	return err
+}
+
+// jsonFieldPath rewrites the decoder's dotted field paths (e.g. "tenants.0.fairness")
+// to match the paths used by validation (e.g. "tenants[0].fairness").
+func jsonFieldPath(field string) string {
+	segments := strings.Split(field, ".")
+	var output strings.Builder
+	for index, segment := range segments {
+		if _, err := strconv.Atoi(segment); err == nil && index > 0 {
+			output.WriteString("[" + segment + "]")
+			continue
+		}
+		if index > 0 {
+			output.WriteString(".")
+		}
+		output.WriteString(segment)
+	}
+	return output.String()
+}
+
+func lineAndColumn(contents []byte, offset int64) (line, column int) {
+	line = 1
+	column = 1
+	for _, b := range contents[:min(offset, int64(len(contents)))] {
+		if b == '\n' {
+			line++
+			column = 1
+			continue
+		}
+		column++
+	}
+	return
+}
+
+func sortedMapKeys[V any](m map[string]V) []string {
+	output := make([]string, 0, len(m))
+	for key := range m {
+		output = append(output, key)
+	}
+	slices.Sort(output)
+	return output
+}
@@ -0,0 +1,208 @@
+package sim
+
+import (
+	"fmt"
+	"time"
+)
+
+// ArrivalSpec is the serializable form of an [ArrivalProcess].
+//
+// Durations are duration strings, e.g. "1m30s".
+type ArrivalSpec struct {
+	// Type is one of "normal", "poisson", "constant", "bursty" or "diurnal".
+	Type string `json:"type"`
+
+	// TasksPerSecond is the rate for the "normal", "poisson" and "constant"
+	// types, and the mean rate for the "diurnal" type.
+	TasksPerSecond float64 `json:"tasksPerSecond,omitempty"`
+
+	OnTasksPerSecond  float64 `json:"onTasksPerSecond,omitempty"`
+	OffTasksPerSecond float64 `json:"offTasksPerSecond,omitempty"`
+	MeanOnDuration    string  `json:"meanOnDuration,omitempty"`
+	MeanOffDuration   string  `json:"meanOffDuration,omitempty"`
+
+	Amplitude  float64 `json:"amplitude,omitempty"`
+	Period     string  `json:"period,omitempty"`
+	PeakOffset string  `json:"peakOffset,omitempty"`
+}
+
+// ArrivalProcess returns a new arrival process for the spec.
+func (as ArrivalSpec) ArrivalProcess(path string) (ArrivalProcess, error) {
+	var errs fieldErrors
+	meanOnDuration := errs.duration(path+".meanOnDuration", as.MeanOnDuration)
+	meanOffDuration := errs.duration(path+".meanOffDuration", as.MeanOffDuration)
+	period := errs.duration(path+".period", as.Period)
+	peakOffset := errs.duration(path+".peakOffset", as.PeakOffset)
+	switch as.Type {
+	case "normal", "poisson", "constant":
+		errs.positive(path+".tasksPerSecond", as.TasksPerSecond)
+	case "bursty":
+		errs.nonNegative(path+".onTasksPerSecond", as.OnTasksPerSecond)
+		errs.nonNegative(path+".offTasksPerSecond", as.OffTasksPerSecond)
+		errs.positive(path+".meanOnDuration", float64(meanOnDuration))
+		errs.positive(path+".meanOffDuration", float64(meanOffDuration))
+	case "diurnal":
+		errs.positive(path+".tasksPerSecond", as.TasksPerSecond)
+		if as.Amplitude < 0 || as.Amplitude > 1 {
+			errs.add(path+".amplitude", "must be between 0 and 1")
+		}
+		errs.positive(path+".period", float64(period))
+	default:
+		errs.add(path+".type", "must be one of normal, poisson, constant, bursty or diurnal, was %q", as.Type)
+	}
+	if err := errs.err(); err != nil {
+		return nil, err
+	}
+	switch as.Type {
+	case "normal":
+		return NormalArrivals{TasksPerSecond: as.TasksPerSecond}, nil
+	case "poisson":
+		return PoissonArrivals{TasksPerSecond: as.TasksPerSecond}, nil
+	case "constant":
+		return ConstantArrivals{TasksPerSecond: as.TasksPerSecond}, nil
+	case "bursty":
+		return &BurstyArrivals{
+			OnTasksPerSecond:  as.OnTasksPerSecond,
+			OffTasksPerSecond: as.OffTasksPerSecond,
+			MeanOnDuration:    meanOnDuration,
+			MeanOffDuration:   meanOffDuration,
+		}, nil
+	default:
+		return DiurnalArrivals{
+			MeanTasksPerSecond: as.TasksPerSecond,
+			Amplitude:          as.Amplitude,
+			Period:             period,
+			PeakOffset:         peakOffset,
+		}, nil
+	}
+}
+
+// DistributionSpec is the serializable form of a [DurationDistribution].
+//
+// Durations are duration strings, e.g. "100ms".
+type DistributionSpec struct {
+	// Type is one of "normal", "lognormal", "exponential", "pareto", "bimodal" or "empirical".
+	Type string `json:"type"`
+
+	// Mean is used by the "normal", "lognormal" and "exponential" types.
+	Mean string `json:"mean,omitempty"`
+	// StdDev is used by the "normal" and "lognormal" types.
+	StdDev string `json:"stdDev,omitempty"`
+
+	Scale string  `json:"scale,omitempty"`
+	Shape float64 `json:"shape,omitempty"`
+	Max   string  `json:"max,omitempty"`
+
+	Fast            *DistributionSpec `json:"fast,omitempty"`
+	Slow            *DistributionSpec `json:"slow,omitempty"`
+	SlowProbability float64           `json:"slowProbability,omitempty"`
+
+	Buckets []EmpiricalBucketSpec `json:"buckets,omitempty"`
+}
+
+// EmpiricalBucketSpec is the serializable form of an [EmpiricalBucket].
+type EmpiricalBucketSpec struct {
+	UpperBound string  `json:"upperBound"`
+	Weight     float64 `json:"weight"`
+}
+
+// DurationDistribution returns a new duration distribution for the spec.
+func (ds DistributionSpec) DurationDistribution(path string) (DurationDistribution, error) {
+	var errs fieldErrors
+	mean := errs.duration(path+".mean", ds.Mean)
+	stdDev := errs.duration(path+".stdDev", ds.StdDev)
+	scale := errs.duration(path+".scale", ds.Scale)
+	maximum := errs.duration(path+".max", ds.Max)
+	switch ds.Type {
+	case "normal":
+		errs.nonNegative(path+".stdDev", float64(stdDev))
+		return NormalDuration{Mean: mean, StdDev: stdDev}, errs.err()
+	case "lognormal":
+		errs.positive(path+".mean", float64(mean))
+		errs.nonNegative(path+".stdDev", float64(stdDev))
+		return LogNormalDurationFromMean(mean, stdDev), errs.err()
+	case "exponential":
+		errs.positive(path+".mean", float64(mean))
+		return ExponentialDuration{Mean: mean}, errs.err()
+	case "pareto":
+		errs.positive(path+".scale", float64(scale))
+		errs.positive(path+".shape", ds.Shape)
+		errs.nonNegative(path+".max", float64(maximum))
+		return ParetoDuration{Scale: scale, Shape: ds.Shape, Max: maximum}, errs.err()
+	case "bimodal":
+		if ds.SlowProbability < 0 || ds.SlowProbability > 1 {
+			errs.add(path+".slowProbability", "must be between 0 and 1")
+		}
+		var fast, slow DurationDistribution
+		var err error
+		if ds.Fast == nil {
+			errs.add(path+".fast", "is required")
+		} else {
+			fast, err = ds.Fast.DurationDistribution(path + ".fast")
+			errs.join(err)
+		}
+		if ds.Slow == nil {
+			errs.add(path+".slow", "is required")
+		} else {
+			slow, err = ds.Slow.DurationDistribution(path + ".slow")
+			errs.join(err)
+		}
+		return BimodalDuration{Fast: fast, Slow: slow, SlowProbability: ds.SlowProbability}, errs.err()
+	case "empirical":
+		if len(ds.Buckets) == 0 {
+			errs.add(path+".buckets", "is required")
+		}
+		buckets := make([]EmpiricalBucket, 0, len(ds.Buckets))
+		var lastUpperBound time.Duration
+		for index, b := range ds.Buckets {
+			bucketPath := fmt.Sprintf("%s.buckets[%d]", path, index)
+			upperBound := errs.duration(bucketPath+".upperBound", b.UpperBound)
+			if upperBound <= lastUpperBound {
+				errs.add(bucketPath+".upperBound", "must be greater than the previous bucket's upper bound")
+			}
+			errs.nonNegative(bucketPath+".weight", b.Weight)
+			lastUpperBound = upperBound
+			buckets = append(buckets, EmpiricalBucket{UpperBound: upperBound, Weight: b.Weight})
+		}
+		return EmpiricalDuration{Buckets: buckets}, errs.err()
+	default:
+		errs.add(path+".type", "must be one of normal, lognormal, exponential, pareto, bimodal or empirical, was %q", ds.Type)
+		return nil, errs.err()
+	}
+}
+
+// AgingSpec is the serializable form of an [AgingPolicy].
+type AgingSpec struct {
+	Thresholds []string `json:"thresholds,omitempty"`
+	Interval   string   `json:"interval,omitempty"`
+}
+
+// AgingPolicy returns the aging policy the spec describes, where errors refer to fields under path.
+func (as *AgingSpec) AgingPolicy(path string) (policy AgingPolicy, err error) {
+	if as == nil {
+		return
+	}
+	var errs fieldErrors
+	for index, value := range as.Thresholds {
+		thresholdPath := fmt.Sprintf("%s.thresholds[%d]", path, index)
+		threshold := errs.duration(thresholdPath, value)
+		errs.positive(thresholdPath, float64(threshold))
+		if index > 0 && threshold <= policy.Thresholds[index-1] {
+			errs.add(thresholdPath, "must be after the previous threshold")
+		}
+		policy.Thresholds = append(policy.Thresholds, threshold)
+	}
+	policy.Interval = errs.duration(path+".interval", as.Interval)
+	errs.nonNegative(path+".interval", float64(policy.Interval))
+	if policy.IsZero() && errs.err() == nil {
+		errs.add(path, "must have thresholds or an interval")
+	}
+	err = errs.err()
+	return
+}
+
+// LimitSpec is the serializable form of a [Limit].
+type LimitSpec struct {
+	Actions uint32 `json:"actions"`
+	Quantum string `json:"quantum"`
+}
@@ -0,0 +1,152 @@
+package sim
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_ParseScenario(t *testing.T) {
+	scenario, err := ParseScenario([]byte(`{
+		"seed": 7,
+		"engine": "event",
+		"duration": "10m",
+		"workerCount": 10,
+		"arrivals": {"type": "poisson", "tasksPerSecond": 100},
+		"taskDuration": {"type": "exponential", "mean": "250ms"},
+		"tenants": [
+			{"fairnessKey": "a", "fairness": 3, "arrivalWeight": 1},
+			{"fairnessKey": "b", "fairness": 1, "arrivals": {"type": "constant", "tasksPerSecond": 10}}
+		],
+		"queue": {"type": "feeder", "costModel": "worker-time", "rateLimits": {"a": {"actions": 30, "quantum": "1s"}}}
+	}`))
+	if err != nil {
+		t.Errorf("expect parse error to be nil, was %v", err)
+		t.FailNow()
+	}
+
+	cfg, err := scenario.SimulationConfig()
+	if err != nil {
+		t.Errorf("expect config error to be nil, was %v", err)
+		t.FailNow()
+	}
+	if cfg.Engine != EngineEvent || cfg.Duration != 10*time.Minute || cfg.WorkerCount != 10 {
+		t.Errorf("expect engine, duration and worker count to be set, was %v, %v, %d", cfg.Engine, cfg.Duration, cfg.WorkerCount)
+		t.Fail()
+	}
+	if cfg.TaskDuration != (ExponentialDuration{Mean: 250 * time.Millisecond}) {
+		t.Errorf("expect task duration to be exponential, was %#v", cfg.TaskDuration)
+		t.Fail()
+	}
+	if len(cfg.Tenants) != 2 || cfg.Tenants[1].Arrivals != (ConstantArrivals{TasksPerSecond: 10}) {
+		t.Errorf("expect two tenants with the second having its own arrivals, was %#v", cfg.Tenants)
+		t.Fail()
+	}
+
+	opts, err := scenario.TaskQueueOptions()
+	if err != nil {
+		t.Errorf("expect options error to be nil, was %v", err)
+		t.FailNow()
+	}
+	if opts.CostModel != CostModelWorkerTime || opts.RateLimits["a"] != (Limit{Actions: 30, Quantum: time.Second}) {
+		t.Errorf("expect cost model and rate limits to be set, was %v, %v", opts.CostModel, opts.RateLimits)
+		t.Fail()
+	}
+
+	s, err := scenario.NewSimulation(NewSimulatedClock(time.Now()))
+	if err != nil {
+		t.Errorf("expect new simulation error to be nil, was %v", err)
+		t.FailNow()
+	}
+	if s.TaskQueue == nil {
+		t.Errorf("expect simulation task queue to be set")
+		t.Fail()
+	}
+}
+
+func Test_ParseScenario_fieldErrors(t *testing.T) {
+	testCases := []struct {
+		Contents string
+		Field    string
+	}{
+		{`{"queue": {"type": "lifo"}}`, "queue.type"},
+		{`{"queue": {"type": "drr"}, "duration": "soon"}`, "duration"},
+		{`{"queue": {"type": "drr"}, "tenants": [{"fairnessKey": 1}]}`, "tenants[0].fairnessKey"},
+		{`{"queue": {"type": "drr"}, "tenants": [{"fairnessKey": "a", "fairness": 1}]}`, "tenants[0]"},
+		{`{"queue": {"type": "drr"}, "priorityWeights": {"P9": 1}}`, "priorityWeights.P9"},
+		{`{"queue": {"type": "drr"}, "taskDuration": {"type": "bimodal", "slowProbability": 0.5, "fast": {"type": "normal"}, "slow": {"type": "pareto", "shape": 2}}}`, "taskDuration.slow.scale"},
+		{`{"queue": {"type": "drr", "rateLimits": {"a": {"actions": 1, "quantum": "1s"}}}}`, "queue.rateLimits"},
+		{`{"queue": {"type": "drr"}, "phases": [{"name": "a", "start": "1m"}, {"name": "b", "start": "30s"}]}`, "phases[1].start"},
+		{`{"queue": {"type": "drr"}, "percentiles": [50, 101]}`, "percentiles[1]"},
+		{`{"queue": {"type": "drr", "aging": {"interval": "5m"}}}`, "queue.aging"},
+		{`{"queue": {"type": "priority", "aging": {"thresholds": ["10m", "5m"]}}}`, "queue.aging.thresholds[1]"},
+		{`{"queue": {"type": "fairness", "priorityClassWeights": {"P5": 1}}}`, "queue.priorityClassWeights.P5"},
+		{`{"queue": {"type": "edf"}, "deadlines": {"P9": "1m"}}`, "deadlines.P9"},
+		{`{"queue": {"type": "drr", "pathWeights": {"acct1": 2}}}`, "queue.pathWeights"},
+		{`{"queue": {"type": "hfq", "pathWeights": {"acct1": 0}}}`, "queue.pathWeights.acct1"},
+		{`{"queue": {"type": "edf"}, "deadlines": {"P1": "-1m"}}`, "deadlines.P1"},
+		{`{"queue": {"type": "drr"}, "phases": [{"name": "a", "start": "2h"}]}`, "phases[0].start"},
+		{`{"queue": {"type": "drr"}, "phases": [{"name": "a", "start": "1m", "concurrencyLimit": -1}]}`, "phases[0].concurrencyLimit"},
+	}
+	for _, tc := range testCases {
+		_, err := ParseScenario([]byte(tc.Contents))
+		var fieldErr FieldError
+		if !errors.As(err, &fieldErr) || fieldErr.Field != tc.Field {
+			t.Errorf("expect %s to have a field error for %q, was %v", tc.Contents, tc.Field, err)
+			t.Fail()
+		}
+	}
+
+	_, err := ParseScenario([]byte(`{"queue": {"type": "drr"}, "workers": 10}`))
+	if err == nil || !strings.Contains(err.Error(), `"workers"`) {
+		t.Errorf("expect unknown fields to be rejected, was %v", err)
+		t.Fail()
+	}
+	_, err = ParseScenario([]byte("{\n  \"queue\": {\"type\": \"drr\"},\n}"))
+	if err == nil || !strings.Contains(err.Error(), "line 3") {
+		t.Errorf("expect syntax errors to include the line, was %v", err)
+		t.Fail()
+	}
+}
+
+func Test_Scenario_NewSimulation_deterministic(t *testing.T) {
+	seed := uint64(42)
+	for _, engine := range []string{"tick", "event"} {
+		for _, queueType := range TaskQueueTypes {
+			scenario := Scenario{
+				Seed:            &seed,
+				Engine:          engine,
+				Duration:        "1m",
+				WorkerCount:     2,
+				TasksPerSecond:  300,
+				PriorityWeights: map[string]int{"P1": 1, "P2": 3, "P4": 1},
+				Tenants: []TenantSpec{
+					{FairnessKey: "a", Fairness: 3, ArrivalWeight: 2},
+					{FairnessKey: "b", Fairness: 2, ArrivalWeight: 1},
+					{FairnessKey: "c", Fairness: 1, ArrivalWeight: 1},
+				},
+				Phases: []PhaseSpec{{Name: "burst", Start: "30s", ArrivalMultipliers: map[string]float64{"c": 5}}},
+				Queue:  QueueSpec{Type: queueType, ConcurrencyLimit: 8},
+			}
+			if queueType == "feeder" {
+				scenario.Queue.RateLimits = map[string]LimitSpec{"a": {Actions: 100, Quantum: "1s"}}
+			}
+
+			var outputs [2]bytes.Buffer
+			for index := range outputs {
+				s, err := scenario.NewSimulation(nil)
+				if err != nil {
+					t.Errorf("%s/%s: expect new simulation error to be nil, was %v", engine, queueType, err)
+					t.FailNow()
+				}
+				_ = WriteResultsJSON(&outputs[index], s.Simulate())
+			}
+			if !bytes.Equal(outputs[0].Bytes(), outputs[1].Bytes()) {
+				t.Errorf("%s/%s: expect runs with the same seed to have identical results", engine, queueType)
+				t.Fail()
+			}
+		}
+	}
+}
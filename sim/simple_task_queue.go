@@ -2,12 +2,12 @@ package sim
 
 func NewSimpleTaskQueue() TaskQueue {
 	return &simpleTaskQueue{
-		storage: &Queue[*Task]{},
+		storage: newKeyedTaskFifo(),
 	}
 }
 
 type simpleTaskQueue struct {
-	storage *Queue[*Task]
+	storage *keyedTaskFifo
 }
 
 func (q *simpleTaskQueue) Len() int {
@@ -19,6 +19,11 @@ func (q *simpleTaskQueue) Push(t Task) {
 }
 
 func (q *simpleTaskQueue) Pull() (task *Task, ok bool) {
-	task, ok = q.storage.Pop()
+	return q.PullFiltered(allowAllFairnessKeys)
+}
+
+// PullFiltered implements [FilteredTaskQueue].
+func (q *simpleTaskQueue) PullFiltered(allow func(fairnessKey string) bool) (task *Task, ok bool) {
+	task, ok = q.storage.Pop(allow)
 	return
 }
@@ -2,8 +2,8 @@ package sim
 
 import (
 	"fmt"
-	"math"
 	"math/rand/v2"
+	"slices"
 	"time"
 )
 
@@ -14,57 +14,139 @@ type Simulation struct {
 	Workers    WorkerLookup
 	RandSource rand.Source
 
-	r *rand.Rand
+	r        *rand.Rand
+	ids      UUIDSource
+	arrivals ArrivalProcess
+	tenants  []TenantProfile
+	// sharedTenantWeights are the arrival weights of the tenants (by index)
+	// that share the simulation's arrival process, and sharedTenants their indexes in order.
+	sharedTenantWeights map[int]int
+	sharedTenants       []int
+	arrivalMultipliers  map[string]float64
+	startTime           time.Time
+	concurrency         *concurrencyStats
+	fairness            *fairnessStats
+	queueLengths        []QueueLengthSample
+	// summary accumulates the results of the whole run as each results bucket closes,
+	// and phaseSummaries the results of the tasks that arrived during each phase.
+	summary        *resultsSummary
+	phaseSummaries []*resultsSummary
+	buckets        []BucketResults
+
+	phases                []Phase
+	phase                 int
+	phaseQueueLengths     []int
+	baseConcurrencyLimit  int
+	baseConcurrencyLimits map[string]int
+	baseRateLimits        map[string]Limit
 }
 
-func (s *Simulation) Init() {
+// Init fills in the defaults of the simulation and resets its state, returning
+// an error if the phases need something of the task queue it does not support.
+func (s *Simulation) Init() error {
 	if s.RandSource == nil {
 		s.RandSource = rand.NewPCG(rand.Uint64(), rand.Uint64())
 	}
 	if s.Clock == nil {
-		s.Clock = NewSimulatedClock(time.Now())
+		s.Clock = NewSimulatedClock(SimulatedStartTime)
 	}
 	if s.TaskQueue == nil {
 		s.TaskQueue = NewSimpleTaskQueue()
 	}
 	s.r = rand.New(s.RandSource)
+	s.ids = UUIDSource{}
+	s.arrivals = s.Config.ArrivalProcessOrDefault()
+	s.setTenants(s.Config.TenantsOrDefault())
+	s.arrivalMultipliers = nil
 	s.Workers = s.generateWorkers()
+	return s.initPhases()
+}
+
+func (s *Simulation) setTenants(tenants []TenantProfile) {
+	s.tenants = tenants
+	s.sharedTenantWeights = make(map[int]int)
+	s.sharedTenants = nil
+	for index, tenant := range s.tenants {
+		if tenant.Arrivals == nil && tenant.ArrivalWeight > 0 {
+			s.sharedTenantWeights[index] = tenant.ArrivalWeight
+			s.sharedTenants = append(s.sharedTenants, index)
+		}
+	}
 }
 
 func (s *Simulation) Simulate() SimulationResults {
+	if s.Config.Engine == EngineEvent {
+		return s.simulateEvents()
+	}
+	return s.simulateTicks()
+}
+
+func (s *Simulation) simulateTicks() SimulationResults {
 	startTime := s.Clock.Now()
+	s.startTime = startTime
+	s.concurrency = newConcurrencyStats(startTime)
+	s.fairness = newFairnessStats(startTime)
+	s.queueLengths = nil
+	s.resetResults()
 	var lastTimestamp, displayLastTimestamp, currentTimestamp time.Time = startTime, startTime, startTime
-	var resultsByBucket resultsByBucket
 
-	var resultState = &results{
-		tasks: make([]*Task, 0, 1_000_000),
-	}
+	var resultState = s.newResults(startTime)
 	for { // hot loop
 		currentTimestamp = s.Clock.Now()
 		if currentTimestamp.Sub(startTime) > s.Config.DurationOrDefault() {
 			break
 		}
+		s.advancePhases(currentTimestamp)
 		s.simulateTick(currentTimestamp, currentTimestamp.Sub(lastTimestamp), resultState)
+		s.sampleQueueLength(currentTimestamp)
 		s.Clock.Wait(s.Config.TickIntervalOrDefault())
 		lastTimestamp = currentTimestamp
 		if displayLastTimestamp.IsZero() {
 			displayLastTimestamp = currentTimestamp
 		} else if currentTimestamp.Sub(displayLastTimestamp) >= s.Config.ResultsBucketingIntervalOrDefault() {
-			log(
-				fmt.Sprintf("closing results bucket (by interval %v)", s.Config.ResultsBucketingIntervalOrDefault()),
-				logTag{"ts", currentTimestamp.Format("15:04")},
-				logTag{"elapsed", currentTimestamp.Sub(startTime)},
-				logTag{"tql", s.TaskQueue.Len()},
-				logTag{"ctp", len(resultState.tasks)},
-			)
-			resultsByBucket = append(resultsByBucket, resultState)
+			s.closeResultsBucket(currentTimestamp, resultState)
 			displayLastTimestamp = currentTimestamp
-			resultState = &results{
-				tasks: make([]*Task, 0, 1_000_000),
-			}
+			resultState = s.newResults(currentTimestamp)
 		}
 	}
-	return s.processResults(currentTimestamp, resultsByBucket)
+	// close the last (partial) bucket
+	if lastTimestamp.After(resultState.start) {
+		s.closeResultsBucket(lastTimestamp, resultState)
+	}
+	return s.processResults(currentTimestamp)
+}
+
+func (s *Simulation) sampleQueueLength(currentTimestamp time.Time) {
+	s.queueLengths = append(s.queueLengths, QueueLengthSample{
+		Elapsed: currentTimestamp.Sub(s.startTime),
+		Length:  s.TaskQueue.Len(),
+	})
+}
+
+// closeResultsBucket summarizes the tasks that completed during a results bucket,
+// and adds them to the results of the whole run.
+func (s *Simulation) closeResultsBucket(currentTimestamp time.Time, state *results) {
+	starvation, fairnessWeights := s.fairness.closeBucket(currentTimestamp)
+	state.summary.demanded(fairnessWeights)
+	s.summary.merge(state.summary)
+	bucket := BucketResults{
+		Start:       state.start.Sub(s.startTime),
+		End:         currentTimestamp.Sub(s.startTime),
+		QueueLength: s.TaskQueue.Len(),
+		Results:     state.summary.results(),
+	}
+	bucket.Results.Fairness.setStarvation(starvation)
+	if elapsed := currentTimestamp.Sub(state.start); elapsed > 0 {
+		bucket.Throughput = float64(bucket.Results.TasksProcessed) / elapsed.Seconds()
+	}
+	s.buckets = append(s.buckets, bucket)
+	log(
+		fmt.Sprintf("closing results bucket (by interval %v)", s.Config.ResultsBucketingIntervalOrDefault()),
+		logTag{"ts", currentTimestamp.Format("15:04")},
+		logTag{"elapsed", currentTimestamp.Sub(s.startTime)},
+		logTag{"tql", bucket.QueueLength},
+		logTag{"ctp", bucket.Results.TasksProcessed},
+	)
 }
 
 func (s *Simulation) generateWorkers() WorkerLookup {
@@ -79,6 +161,17 @@ func (s *Simulation) generateWorkers() WorkerLookup {
 	return output
 }
 
+// workersByID returns the workers in order, as iterating the lookup would visit them in a random order.
+func (s *Simulation) workersByID() []*Worker {
+	workers := make([]*Worker, 0, len(s.Workers))
+	for x := 0; x < len(s.Workers); x++ {
+		if w, ok := s.Workers[x]; ok {
+			workers = append(workers, w)
+		}
+	}
+	return workers
+}
+
 func (s *Simulation) simulateTick(currentTimestamp time.Time, elapsedSinceLastTick time.Duration, state *results) {
 	s.tickTaskArrivals(currentTimestamp, elapsedSinceLastTick)
 	s.tickWorkerPoll(currentTimestamp)
@@ -86,21 +179,61 @@ func (s *Simulation) simulateTick(currentTimestamp time.Time, elapsedSinceLastTi
 }
 
 func (s *Simulation) tickTaskArrivals(currentTimestamp time.Time, elapsedSinceLastTick time.Duration) {
-	newTaskCount := s.randomNewTaskCount(elapsedSinceLastTick)
-	for x := 0; x < newTaskCount; x++ {
-		t := Task{
-			ID:           NewUUID(),
-			CreatedUTC:   currentTimestamp,
-			Priority:     s.randomPriority(),
-			WorkDuration: s.randomWorkDuration(),
+	offset := currentTimestamp.Sub(s.startTime) - elapsedSinceLastTick
+	s.forEachArrival(offset, elapsedSinceLastTick, func(tenant *TenantProfile) {
+		s.push(s.newTask(tenant, currentTimestamp))
+	})
+}
+
+// forEachArrival calls a given function with the tenant of each task
+// arriving in the window that starts the given offset after the start of the simulation.
+func (s *Simulation) forEachArrival(offset, window time.Duration, fn func(*TenantProfile)) {
+	if len(s.sharedTenantWeights) > 0 {
+		newTaskCount := s.arrivals.Arrivals(s.r, offset, window)
+		for x := 0; x < newTaskCount; x++ {
+			tenant := &s.tenants[RandomKeyByWeight(s.r, s.sharedTenants, s.sharedTenantWeights)]
+			for y := s.scaleArrivals(tenant, 1); y > 0; y-- {
+				fn(tenant)
+			}
 		}
-		t.FairnessKey, t.Fairness = s.randomFairness()
-		s.TaskQueue.Push(t)
 	}
+	for index := range s.tenants {
+		if s.tenants[index].Arrivals == nil {
+			continue
+		}
+		newTaskCount := s.scaleArrivals(&s.tenants[index], s.tenants[index].Arrivals.Arrivals(s.r, offset, window))
+		for x := 0; x < newTaskCount; x++ {
+			fn(&s.tenants[index])
+		}
+	}
+}
+
+func (s *Simulation) newTask(tenant *TenantProfile, createdUTC time.Time) Task {
+	t := Task{
+		ID:          s.ids.NewUUID(),
+		CreatedUTC:  createdUTC,
+		FairnessKey: tenant.FairnessKey,
+		Fairness:    tenant.Fairness,
+		Priority:    s.randomPriority(tenant.PriorityWeights),
+	}
+	if tenant.TaskDuration != nil {
+		t.WorkDuration = tenant.TaskDuration.Sample(s.r)
+	} else {
+		t.WorkDuration = s.Config.TaskDurationFor(t.FairnessKey, t.Priority).Sample(s.r)
+	}
+	if deadline, ok := s.Config.DeadlineByPriority[t.Priority]; ok {
+		t.Deadline = createdUTC.Add(deadline)
+	}
+	return t
+}
+
+func (s *Simulation) push(t Task) {
+	s.fairness.pushed(t.CreatedUTC, t.FairnessKey, t.Fairness)
+	s.TaskQueue.Push(t)
 }
 
 func (s *Simulation) tickWorkerPoll(currentTimestamp time.Time) {
-	for _, w := range s.Workers {
+	for _, w := range s.workersByID() {
 		for len(w.Tasks) < w.MaxTasks {
 			t, ok := s.TaskQueue.Pull()
 			if !ok {
@@ -108,69 +241,61 @@ func (s *Simulation) tickWorkerPoll(currentTimestamp time.Time) {
 			}
 			t.DispatchedUTC = currentTimestamp
 			w.Tasks.Add(t)
+			s.concurrency.dispatched(currentTimestamp, t.FairnessKey)
+			s.fairness.dispatched(currentTimestamp, t.FairnessKey)
 		}
 	}
 }
 
 func (s *Simulation) tickWorkerComplete(currentTimestamp time.Time, state *results) {
-	for _, w := range s.Workers {
+	observer, _ := s.TaskQueue.(CompletionObserver)
+	for _, w := range s.workersByID() {
 		var completed []*Task
 		for _, t := range w.Tasks {
 			if currentTimestamp.Sub(t.DispatchedUTC) >= t.WorkDuration {
 				completed = append(completed, t)
 			}
 		}
+		slices.SortFunc(completed, func(a, b *Task) int { return a.ID.Compare(b.ID) })
 		for _, t := range completed {
 			t.CompletedUTC = currentTimestamp
 			w.Tasks.Del(t)
-			state.push(t)
+			s.concurrency.completed(currentTimestamp, t.FairnessKey)
+			if observer != nil {
+				observer.OnComplete(t)
+			}
+			s.completed(state, t)
 		}
 	}
 }
 
-func (s *Simulation) randomFairness() (fairnessKey string, fairness float64) {
-	if len(s.Config.FairnessWeights) == 0 {
-		return "", 1.0
+func (s *Simulation) randomPriority(priorityWeights map[Priority]int) Priority {
+	if len(priorityWeights) == 0 {
+		priorityWeights = s.Config.PriorityWeights
 	}
-	fairnessKey = s.randomFairnessKey()
-	fairness = s.Config.FairnessWeights[fairnessKey]
-	return
-}
-
-func (s *Simulation) randomFairnessKey() string {
-	if len(s.Config.FairnessKeyWeights) == 0 {
-		return ""
-	}
-	return RandomKeyByWeight(s.r, s.Config.FairnessKeyWeights)
-}
-
-func (s *Simulation) randomPriority() Priority {
-	if len(s.Config.PriorityWeights) == 0 {
+	if len(priorityWeights) == 0 {
 		return P2
 	}
-	return RandomKeyByWeight(s.r, s.Config.PriorityWeights)
-}
-
-func (s *Simulation) randomNewTaskCount(elapsedSinceLastTick time.Duration) int {
-	elapsedSeconds := float64(elapsedSinceLastTick) / float64(time.Second)
-	tasksMean := math.Floor(float64(s.Config.TasksPerSecondOrDefault()) * elapsedSeconds)
-	return int(s.randomNormal(tasksMean, tasksMean))
+	return RandomKeyByWeight(s.r, priorities, priorityWeights)
 }
 
-func (s *Simulation) randomWorkDuration() time.Duration {
-	return time.Duration(RandomNormal(s.r, float64(s.Config.TaskDurationMeanOrDefault()), float64(s.Config.TaskDurationStdDevOrDefault())))
-}
-
-func (s *Simulation) randomNormal(desiredMean, desiredStdDev float64) float64 {
-	return RandomNormal(s.r, desiredMean, desiredStdDev)
+func (s *Simulation) newResults(start time.Time) *results {
+	return &results{
+		start:   start,
+		summary: newResultsSummary(s.Config.PercentilesOrDefault()),
+	}
 }
 
-type resultsByBucket []*results
-
+// results are the results of the tasks that complete during a results bucket.
 type results struct {
-	tasks []*Task
+	start   time.Time
+	summary *resultsSummary
 }
 
-func (r *results) push(t *Task) {
-	r.tasks = append(r.tasks, t)
+// completed adds a task that completed to the results of its bucket and phase.
+func (s *Simulation) completed(state *results, t *Task) {
+	state.summary.completed(t)
+	if len(s.phases) > 0 {
+		s.phaseSummaries[s.phaseAt(t.CreatedUTC.Sub(s.startTime))].completed(t)
+	}
 }
@@ -4,12 +4,42 @@ import "time"
 
 // SimulationConfig are parameters to the simulation.
 type SimulationConfig struct {
+	// Engine is the method by which the simulation advances time.
+	//
+	// With the event engine, the tick interval is only used as the window
+	// over which task arrivals are drawn.
+	Engine Engine
+
 	Duration                 time.Duration
 	TickInterval             time.Duration
 	ResultsBucketingInterval time.Duration
 	TaskDurationMean         time.Duration
 	TaskDurationStdDev       time.Duration
 
+	// TaskDuration is the distribution task work durations are drawn from, and defaults
+	// to [NormalDuration] with the configured task duration mean and std dev.
+	TaskDuration DurationDistribution
+	// TaskDurationByFairnessKey overrides the task duration distribution for
+	// specific fairness keys, and takes precedence over TaskDurationByPriority.
+	TaskDurationByFairnessKey map[string]DurationDistribution
+	// TaskDurationByPriority overrides the task duration distribution for specific priorities.
+	TaskDurationByPriority map[Priority]DurationDistribution
+
+	// ArrivalProcess determines how many tasks arrive over time, and defaults
+	// to [NormalArrivals] at the configured tasks per second.
+	ArrivalProcess ArrivalProcess
+
+	// Tenants describes the workload of each fairness key; if unset, the tenants
+	// are derived from the fairness key and fairness weights.
+	Tenants []TenantProfile
+
+	// Phases change the workload, capacity or task queue limits at given offsets into the simulation.
+	Phases []Phase
+
+	// DeadlineByPriority is how soon after it is created a task of each priority should be
+	// dispatched, e.g. "P1 work starts within 30s"; tasks of other priorities have no deadline.
+	DeadlineByPriority map[Priority]time.Duration
+
 	PriorityWeights    map[Priority]int
 	FairnessKeyWeights map[string]int
 	FairnessWeights    map[string]float64
@@ -17,6 +47,9 @@ type SimulationConfig struct {
 	WorkerCount     int
 	WorkerTaskSlots int
 	TasksPerSecond  int
+
+	// Percentiles are the percentiles latency distributions are reported at, e.g. 99.9.
+	Percentiles []float64
 }
 
 func (sc SimulationConfig) DurationOrDefault() time.Duration {
@@ -26,6 +59,13 @@ func (sc SimulationConfig) DurationOrDefault() time.Duration {
 	return 1 * time.Hour
 }
 
+func (sc SimulationConfig) PercentilesOrDefault() []float64 {
+	if len(sc.Percentiles) > 0 {
+		return sc.Percentiles
+	}
+	return []float64{50, 90, 99, 99.9}
+}
+
 func (sc SimulationConfig) TickIntervalOrDefault() time.Duration {
 	if sc.TickInterval > 0 {
 		return sc.TickInterval
@@ -74,3 +114,28 @@ func (sc SimulationConfig) TasksPerSecondOrDefault() int {
 	}
 	return 3200
 }
+
+func (sc SimulationConfig) TaskDurationOrDefault() DurationDistribution {
+	if sc.TaskDuration != nil {
+		return sc.TaskDuration
+	}
+	return NormalDuration{Mean: sc.TaskDurationMeanOrDefault(), StdDev: sc.TaskDurationStdDevOrDefault()}
+}
+
+// TaskDurationFor returns the task duration distribution for a given fairness key and priority.
+func (sc SimulationConfig) TaskDurationFor(fairnessKey string, priority Priority) DurationDistribution {
+	if dd, ok := sc.TaskDurationByFairnessKey[fairnessKey]; ok {
+		return dd
+	}
+	if dd, ok := sc.TaskDurationByPriority[priority]; ok {
+		return dd
+	}
+	return sc.TaskDurationOrDefault()
+}
+
+func (sc SimulationConfig) ArrivalProcessOrDefault() ArrivalProcess {
+	if sc.ArrivalProcess != nil {
+		return sc.ArrivalProcess
+	}
+	return NormalArrivals{TasksPerSecond: float64(sc.TasksPerSecondOrDefault())}
+}
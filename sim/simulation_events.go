@@ -0,0 +1,236 @@
+package sim
+
+import (
+	"cmp"
+	"slices"
+	"time"
+)
+
+// simulateEvents runs the simulation with the discrete event engine.
+//
+// Arrivals are drawn per tick interval window, as with the tick engine, but each
+// arriving task is given its own uniformly distributed arrival time within the window.
+// The clock then jumps from event to event, dispatching tasks as soon as they arrive
+// or a worker task slot frees up, and completing tasks exactly after their work duration.
+// While the task queue holds back tasks with task slots free, dispatches are retried every tick interval.
+func (s *Simulation) simulateEvents() SimulationResults {
+	startTime := s.Clock.Now()
+	endTime := startTime.Add(s.Config.DurationOrDefault())
+	s.startTime = startTime
+	s.concurrency = newConcurrencyStats(startTime)
+	s.fairness = newFairnessStats(startTime)
+	s.queueLengths = nil
+	s.resetResults()
+
+	resultState := s.newResults(startTime)
+	observer, _ := s.TaskQueue.(CompletionObserver)
+	slots := s.generateWorkerSlots()
+	events := newEventQueue(startTime)
+	s.advancePhases(startTime)
+	for _, phase := range s.phases {
+		if phase.Start > 0 {
+			events.push(event{at: startTime.Add(phase.Start), kind: eventPhase})
+		}
+	}
+	events.push(event{at: startTime, kind: eventArrivals})
+	events.push(event{at: startTime.Add(s.Config.ResultsBucketingIntervalOrDefault()), kind: eventCloseBucket})
+
+	// dispatchAt is the time of the next dispatch, if one is pending; an earlier
+	// dispatch supersedes it, e.g. when a task completes before a retry is due.
+	var dispatchPending bool
+	var dispatchAt time.Time
+	scheduleDispatch := func(at time.Time) {
+		if slots.Len() == 0 || dispatchPending && !at.Before(dispatchAt) {
+			return
+		}
+		dispatchPending, dispatchAt = true, at
+		events.push(event{at: at, kind: eventDispatch})
+	}
+
+	for { // hot loop
+		e, ok := events.pop()
+		if !ok || e.at.After(endTime) {
+			break
+		}
+		if wait := e.at.Sub(s.Clock.Now()); wait > 0 {
+			s.Clock.Wait(wait)
+		}
+		switch e.kind {
+		case eventArrivals:
+			s.sampleQueueLength(e.at)
+			window := s.Config.TickIntervalOrDefault()
+			var arrivals []arrival
+			s.forEachArrival(e.at.Sub(startTime), window, func(tenant *TenantProfile) {
+				arrivals = append(arrivals, arrival{
+					offset: time.Duration(s.r.Int64N(int64(window))),
+					tenant: tenant,
+				})
+			})
+			events.pushArrivals(e.at, arrivals)
+			events.push(event{at: e.at.Add(window), kind: eventArrivals})
+		case eventArrival:
+			s.push(s.newTask(e.tenant, e.at))
+			scheduleDispatch(e.at)
+		case eventDispatch:
+			if !dispatchPending || !e.at.Equal(dispatchAt) {
+				// superseded by an earlier dispatch.
+				break
+			}
+			dispatchPending = false
+			for slots.Len() > 0 {
+				t, ok := s.TaskQueue.Pull()
+				if !ok {
+					break
+				}
+				w, _ := slots.Pop()
+				t.DispatchedUTC = e.at
+				w.Tasks.Add(t)
+				s.concurrency.dispatched(e.at, t.FairnessKey)
+				s.fairness.dispatched(e.at, t.FairnessKey)
+				events.push(event{at: e.at.Add(max(t.WorkDuration, 0)), kind: eventCompletion, task: t, worker: w})
+			}
+			if s.TaskQueue.Len() > 0 {
+				// the task queue held back tasks with task slots free (e.g. for a rate limit),
+				// and may allow them later without another event, so retry a tick later as the tick engine does.
+				scheduleDispatch(e.at.Add(s.Config.TickIntervalOrDefault()))
+			}
+		case eventCompletion:
+			e.task.CompletedUTC = e.at
+			e.worker.Tasks.Del(e.task)
+			if len(e.worker.Tasks) < e.worker.MaxTasks {
+				slots.Push(e.worker)
+			}
+			s.concurrency.completed(e.at, e.task.FairnessKey)
+			if observer != nil {
+				observer.OnComplete(e.task)
+			}
+			s.completed(resultState, e.task)
+			scheduleDispatch(e.at)
+		case eventPhase:
+			if s.advancePhases(e.at) {
+				// the worker count may have changed.
+				slots = s.generateWorkerSlots()
+				scheduleDispatch(e.at)
+			}
+		case eventCloseBucket:
+			s.closeResultsBucket(e.at, resultState)
+			resultState = s.newResults(e.at)
+			events.push(event{at: e.at.Add(s.Config.ResultsBucketingIntervalOrDefault()), kind: eventCloseBucket})
+		}
+	}
+	if wait := endTime.Sub(s.Clock.Now()); wait > 0 {
+		s.Clock.Wait(wait)
+	}
+	// close the last (partial) bucket
+	if endTime.After(resultState.start) {
+		s.closeResultsBucket(endTime, resultState)
+	}
+	return s.processResults(endTime)
+}
+
+// generateWorkerSlots returns a queue with an entry for each free worker task slot,
+// interleaving the workers such that load is spread evenly.
+func (s *Simulation) generateWorkerSlots() *Queue[*Worker] {
+	workers := s.workersByID()
+	slots := &Queue[*Worker]{}
+	for slot := 0; slot < s.Config.WorkerTaskSlotsOrDefault(); slot++ {
+		for _, w := range workers {
+			if slot < w.MaxTasks-len(w.Tasks) {
+				slots.Push(w)
+			}
+		}
+	}
+	return slots
+}
+
+type eventKind int
+
+const (
+	// eventArrivals draws the task arrivals for the next arrival window.
+	eventArrivals eventKind = iota
+	eventArrival
+	eventDispatch
+	eventCompletion
+	eventCloseBucket
+	// eventPhase enters the next phase of the simulation.
+	eventPhase
+)
+
+type event struct {
+	at     time.Time
+	offset time.Duration
+	seq    uint64
+	kind   eventKind
+	task   *Task
+	worker *Worker
+	tenant *TenantProfile
+}
+
+// arrival is a task arrival for a tenant at an offset (within an arrival window).
+type arrival struct {
+	offset time.Duration
+	tenant *TenantProfile
+}
+
+// eventQueue is a heap of events ordered by time, and then by the order they were pushed.
+//
+// Individual task arrivals are by far the most common events, so rather than going through
+// the heap they are kept in a separate time-sorted fifo and merged with the heap as events are popped.
+type eventQueue struct {
+	start    time.Time
+	seq      uint64
+	heap     *Heap[*event]
+	arrivals *Queue[arrival]
+}
+
+func newEventQueue(start time.Time) *eventQueue {
+	return &eventQueue{
+		start: start,
+		heap: NewHeap(func(i, j *event) bool {
+			if i.offset != j.offset {
+				return i.offset < j.offset
+			}
+			return i.seq < j.seq
+		}),
+		arrivals: &Queue[arrival]{},
+	}
+}
+
+func (eq *eventQueue) push(e event) {
+	eq.seq++
+	e.seq = eq.seq
+	e.offset = e.at.Sub(eq.start)
+	eq.heap.Push(&e)
+}
+
+// pushArrivals adds arrival events at the given offsets from a window start time.
+//
+// The windows must be pushed in order and must not overlap.
+func (eq *eventQueue) pushArrivals(windowStart time.Time, arrivals []arrival) {
+	slices.SortStableFunc(arrivals, func(i, j arrival) int {
+		return cmp.Compare(i.offset, j.offset)
+	})
+	base := windowStart.Sub(eq.start)
+	for _, a := range arrivals {
+		a.offset += base
+		eq.arrivals.Push(a)
+	}
+}
+
+func (eq *eventQueue) pop() (e event, ok bool) {
+	next, hasNext := eq.heap.Peek()
+	a, hasArrival := eq.arrivals.Peek()
+	if hasArrival && (!hasNext || a.offset <= next.offset) {
+		_, _ = eq.arrivals.Pop()
+		e = event{at: eq.start.Add(a.offset), offset: a.offset, kind: eventArrival, tenant: a.tenant}
+		ok = true
+		return
+	}
+	if !hasNext {
+		return
+	}
+	_, _ = eq.heap.Pop()
+	e = *next
+	ok = true
+	return
+}
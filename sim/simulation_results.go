@@ -8,62 +8,364 @@ type SimulationResults struct {
 
 	CountByPriority    map[Priority]int
 	CountByFairnessKey map[string]int
+	// BoostedByPriority is the number of tasks of each priority that an [AgingPolicy]
+	// had raised to a higher priority when they were dispatched.
+	BoostedByPriority map[Priority]int
 
 	QueuedAvg time.Duration
+	QueuedP50 time.Duration
 	QueuedP95 time.Duration
+	QueuedP99 time.Duration
 
 	QueuedAvgByPriority map[Priority]time.Duration
+	QueuedP50ByPriority map[Priority]time.Duration
 	QueuedP95ByPriority map[Priority]time.Duration
+	QueuedP99ByPriority map[Priority]time.Duration
 
 	QueuedAvgByFairnessKey map[string]time.Duration
+	QueuedP50ByFairnessKey map[string]time.Duration
 	QueuedP95ByFairnessKey map[string]time.Duration
+	QueuedP99ByFairnessKey map[string]time.Duration
+
+	// WorkerTimeByFairnessKey is the total time tasks for each
+	// fairness key occupied a worker task slot.
+	WorkerTimeByFairnessKey map[string]time.Duration
+
+	// CountByFairnessPath, WorkerTimeByFairnessPath and LatenciesByFairnessPath aggregate the
+	// fairness keys under each parent path of hierarchical fairness keys, e.g. "acct1" and
+	// "acct1/merchantA" for "acct1/merchantA/export"; flat fairness keys have no parent paths.
+	CountByFairnessPath      map[string]int
+	WorkerTimeByFairnessPath map[string]time.Duration
+	LatenciesByFairnessPath  map[string]Latencies
+
+	// ConcurrencyMaxByFairnessKey is the most tasks for each fairness key
+	// held in worker task slots at once.
+	ConcurrencyMaxByFairnessKey map[string]int
+	// ConcurrencyAvgByFairnessKey is the time weighted average number of tasks for
+	// each fairness key held in worker task slots.
+	ConcurrencyAvgByFairnessKey map[string]float64
+
+	// Percentiles are the percentiles the latency distributions are reported at.
+	Percentiles []float64
+	// Latencies are the queued, service and end-to-end latency distributions at the
+	// percentiles, overall and by priority and fairness key.
+	Latencies              Latencies
+	LatenciesByPriority    map[Priority]Latencies
+	LatenciesByFairnessKey map[string]Latencies
+
+	// QueuedQuantilesByPriority are the queued times at each whole percentile from 0 to 100.
+	QueuedQuantilesByPriority map[Priority][]time.Duration
+	// QueuedQuantilesByFairnessKey are the queued times at each whole percentile from 0 to 100.
+	QueuedQuantilesByFairnessKey map[string][]time.Duration
+
+	// Fairness summarizes how fairly the fairness keys were served.
+	Fairness FairnessResults
+
+	// Deadlines count the tasks with a deadline that missed it, overall and by priority and fairness key.
+	Deadlines              DeadlineResults
+	DeadlinesByPriority    map[Priority]DeadlineResults
+	DeadlinesByFairnessKey map[string]DeadlineResults
+
+	// QueueLengths is the task queue length sampled every tick interval.
+	QueueLengths []QueueLengthSample
+
+	// Buckets are the results broken out by results bucketing interval.
+	Buckets []BucketResults
+	// Phases are the results broken out by phase, if the simulation has phases.
+	Phases []PhaseResults
 }
 
-func (s *Simulation) processResults(finalTimestamp time.Time, state resultsByBucket) (res SimulationResults) {
-	res.CountByPriority = make(map[Priority]int)
-	res.CountByFairnessKey = make(map[string]int)
+// QueueLengthSample is the task queue length at an offset into the simulation.
+type QueueLengthSample struct {
+	Elapsed time.Duration
+	Length  int
+}
 
-	res.QueuedAvgByPriority = make(map[Priority]time.Duration)
-	res.QueuedP95ByPriority = make(map[Priority]time.Duration)
+// BucketResults are the results for the tasks that completed during
+// a results bucketing interval of the simulation.
+type BucketResults struct {
+	Start time.Duration
+	End   time.Duration
+	// QueueLength is the task queue length when the bucket closed.
+	QueueLength int
+	// Throughput is the number of tasks completed per second.
+	Throughput float64
 
-	res.QueuedAvgByFairnessKey = make(map[string]time.Duration)
-	res.QueuedP95ByFairnessKey = make(map[string]time.Duration)
+	// Results are the results for the tasks that completed during the bucket;
+	// tasks still queued when the bucket closed are not included, although their
+	// fairness keys are included in the fairness results.
+	//
+	// Concurrency is not broken out by bucket.
+	Results SimulationResults
+}
 
-	allQueued := []time.Duration{}
-	queuedByPriority := make(map[Priority][]time.Duration)
-	queuedByFairnessKey := make(map[string][]time.Duration)
+// PhaseResults are the results for the tasks that arrived during a phase of the simulation.
+type PhaseResults struct {
+	Name  string
+	Start time.Duration
+	End   time.Duration
+	// QueueLength is the task queue length at the end of the phase.
+	QueueLength int
 
-	for _, hour := range state {
-		res.TasksProcessed += len(hour.tasks)
-		for _, t := range hour.tasks {
-			queued := t.DispatchedUTC.Sub(t.CreatedUTC)
-			allQueued = append(allQueued, queued)
-			res.CountByPriority[t.Priority]++
-			res.CountByFairnessKey[t.FairnessKey]++
-			queuedByPriority[t.Priority] = append(queuedByPriority[t.Priority], queued)
-			queuedByFairnessKey[t.FairnessKey] = append(queuedByFairnessKey[t.FairnessKey], queued)
+	// Results are the results for the tasks that arrived during the phase, where
+	// tasks still queued at the end of the simulation are counted as queued until the end.
+	//
+	// Concurrency and starvation are not broken out by phase.
+	Results SimulationResults
+}
+
+// resetResults starts accumulating the results of a new run.
+func (s *Simulation) resetResults() {
+	s.summary = newResultsSummary(s.Config.PercentilesOrDefault())
+	s.phaseSummaries = make([]*resultsSummary, len(s.phases))
+	for index := range s.phaseSummaries {
+		s.phaseSummaries[index] = newResultsSummary(s.Config.PercentilesOrDefault())
+	}
+	s.buckets = nil
+}
+
+func (s *Simulation) processResults(finalTimestamp time.Time) (res SimulationResults) {
+	if len(s.phases) > 0 {
+		s.phaseQueueLengths = append(s.phaseQueueLengths, s.TaskQueue.Len())
+	}
+	for _, t := range DrainTaskQueue(s.TaskQueue) {
+		s.summary.queued(t, finalTimestamp)
+		if len(s.phases) > 0 {
+			s.phaseSummaries[s.phaseAt(t.CreatedUTC.Sub(s.startTime))].queued(t, finalTimestamp)
 		}
 	}
-	for s.TaskQueue.Len() > 0 {
-		t, ok := s.TaskQueue.Pull()
-		if !ok {
-			break
+
+	s.summary.demanded(s.fairness.weights)
+	s.fairness.starvedUntil(finalTimestamp)
+
+	res = s.summary.results()
+	res.Fairness.setStarvation(s.fairness.longest)
+	res.Buckets = s.buckets
+	res.ElapsedTime = finalTimestamp.Sub(s.startTime)
+	res.QueueLengths = s.queueLengths
+	res.ConcurrencyMaxByFairnessKey = s.concurrency.max
+	res.ConcurrencyAvgByFairnessKey = s.concurrency.avg(finalTimestamp)
+	for index, phase := range s.phases {
+		phaseResults := PhaseResults{
+			Name:    phase.Name,
+			Start:   phase.Start,
+			End:     s.phaseEnd(index),
+			Results: s.phaseSummaries[index].results(),
+		}
+		if index < len(s.phaseQueueLengths) {
+			phaseResults.QueueLength = s.phaseQueueLengths[index]
 		}
-		queued := finalTimestamp.Sub(t.CreatedUTC)
-		allQueued = append(allQueued, queued)
-		res.CountByPriority[t.Priority]++
-		queuedByPriority[t.Priority] = append(queuedByPriority[t.Priority], queued)
-		queuedByFairnessKey[t.FairnessKey] = append(queuedByFairnessKey[t.FairnessKey], queued)
-	}
-	for p, times := range queuedByPriority {
-		res.QueuedAvgByPriority[p] = AvgDurations(times)
-		res.QueuedP95ByPriority[p] = p95(times)
-	}
-	for key, times := range queuedByFairnessKey {
-		res.QueuedAvgByFairnessKey[key] = AvgDurations(times)
-		res.QueuedP95ByFairnessKey[key] = p95(times)
-	}
-	res.QueuedAvg = AvgDurations(allQueued)
-	res.QueuedP95 = p95(allQueued)
+		res.Phases = append(res.Phases, phaseResults)
+	}
 	return
 }
+
+// resultsSummary accumulates the latencies of tasks into results, in memory that does
+// not grow with the number of tasks.
+type resultsSummary struct {
+	res         SimulationResults
+	percentiles []float64
+	// fairnessWeights are the weights of the fairness keys that had tasks.
+	fairnessWeights map[string]float64
+	all             latencyHistograms
+	byPriority      map[Priority]*latencyHistograms
+	byFairnessKey   map[string]*latencyHistograms
+	byFairnessPath  map[string]*latencyHistograms
+}
+
+func newResultsSummary(percentiles []float64) *resultsSummary {
+	return &resultsSummary{
+		res: SimulationResults{
+			CountByPriority:        make(map[Priority]int),
+			CountByFairnessKey:     make(map[string]int),
+			BoostedByPriority:      make(map[Priority]int),
+			QueuedAvgByPriority:    make(map[Priority]time.Duration),
+			QueuedP50ByPriority:    make(map[Priority]time.Duration),
+			QueuedP95ByPriority:    make(map[Priority]time.Duration),
+			QueuedP99ByPriority:    make(map[Priority]time.Duration),
+			QueuedAvgByFairnessKey: make(map[string]time.Duration),
+			QueuedP50ByFairnessKey: make(map[string]time.Duration),
+			QueuedP95ByFairnessKey: make(map[string]time.Duration),
+			QueuedP99ByFairnessKey: make(map[string]time.Duration),
+			LatenciesByPriority:    make(map[Priority]Latencies),
+			LatenciesByFairnessKey: make(map[string]Latencies),
+			DeadlinesByPriority:    make(map[Priority]DeadlineResults),
+			DeadlinesByFairnessKey: make(map[string]DeadlineResults),
+
+			QueuedQuantilesByPriority:    make(map[Priority][]time.Duration),
+			QueuedQuantilesByFairnessKey: make(map[string][]time.Duration),
+			WorkerTimeByFairnessKey:      make(map[string]time.Duration),
+			CountByFairnessPath:          make(map[string]int),
+			WorkerTimeByFairnessPath:     make(map[string]time.Duration),
+			LatenciesByFairnessPath:      make(map[string]Latencies),
+			Percentiles:                  percentiles,
+		},
+		percentiles:     percentiles,
+		fairnessWeights: make(map[string]float64),
+		byPriority:      make(map[Priority]*latencyHistograms),
+		byFairnessKey:   make(map[string]*latencyHistograms),
+		byFairnessPath:  make(map[string]*latencyHistograms),
+	}
+}
+
+// completed adds a task that was processed.
+func (rs *resultsSummary) completed(t *Task) {
+	rs.res.TasksProcessed++
+	rs.res.CountByPriority[t.Priority]++
+	rs.res.CountByFairnessKey[t.FairnessKey]++
+	if t.Boost > 0 {
+		rs.res.BoostedByPriority[t.Priority]++
+	}
+	rs.res.WorkerTimeByFairnessKey[t.FairnessKey] += t.CompletedUTC.Sub(t.DispatchedUTC)
+	rs.fairnessWeights[t.FairnessKey] = t.Fairness
+	rs.all.completed(t)
+	rs.priority(t.Priority).completed(t)
+	rs.fairnessKey(t.FairnessKey).completed(t)
+	for _, path := range FairnessPathParents(t.FairnessKey) {
+		rs.res.CountByFairnessPath[path]++
+		rs.res.WorkerTimeByFairnessPath[path] += t.CompletedUTC.Sub(t.DispatchedUTC)
+		rs.fairnessPath(path).completed(t)
+	}
+	if hasDeadline, missed := deadlineMissed(t, t.DispatchedUTC); hasDeadline {
+		rs.deadline(t, missed)
+	}
+}
+
+// queued adds a task that was still queued at the end of the simulation.
+func (rs *resultsSummary) queued(t *Task, finalTimestamp time.Time) {
+	rs.res.CountByPriority[t.Priority]++
+	rs.fairnessWeights[t.FairnessKey] = t.Fairness
+	rs.all.stillQueued(t, finalTimestamp)
+	rs.priority(t.Priority).stillQueued(t, finalTimestamp)
+	rs.fairnessKey(t.FairnessKey).stillQueued(t, finalTimestamp)
+	for _, path := range FairnessPathParents(t.FairnessKey) {
+		rs.fairnessPath(path).stillQueued(t, finalTimestamp)
+	}
+	if hasDeadline, missed := deadlineMissed(t, finalTimestamp); hasDeadline && missed {
+		rs.deadline(t, missed)
+	}
+}
+
+// deadline adds a task with a deadline, and whether it missed it.
+func (rs *resultsSummary) deadline(t *Task, missed bool) {
+	rs.res.Deadlines.add(missed)
+	byPriority := rs.res.DeadlinesByPriority[t.Priority]
+	byPriority.add(missed)
+	rs.res.DeadlinesByPriority[t.Priority] = byPriority
+	byFairnessKey := rs.res.DeadlinesByFairnessKey[t.FairnessKey]
+	byFairnessKey.add(missed)
+	rs.res.DeadlinesByFairnessKey[t.FairnessKey] = byFairnessKey
+}
+
+// demanded adds fairness keys that had tasks, though maybe none that were processed or
+// still queued, e.g. the keys with tasks queued during a bucket.
+func (rs *resultsSummary) demanded(fairnessWeights map[string]float64) {
+	for key, weight := range fairnessWeights {
+		rs.fairnessWeights[key] = weight
+	}
+}
+
+// merge adds the tasks of another summary, e.g. of a results bucket.
+func (rs *resultsSummary) merge(other *resultsSummary) {
+	rs.res.TasksProcessed += other.res.TasksProcessed
+	for p, count := range other.res.CountByPriority {
+		rs.res.CountByPriority[p] += count
+	}
+	for key, count := range other.res.CountByFairnessKey {
+		rs.res.CountByFairnessKey[key] += count
+	}
+	for p, count := range other.res.BoostedByPriority {
+		rs.res.BoostedByPriority[p] += count
+	}
+	rs.res.Deadlines.merge(other.res.Deadlines)
+	for p, deadlines := range other.res.DeadlinesByPriority {
+		merged := rs.res.DeadlinesByPriority[p]
+		merged.merge(deadlines)
+		rs.res.DeadlinesByPriority[p] = merged
+	}
+	for key, deadlines := range other.res.DeadlinesByFairnessKey {
+		merged := rs.res.DeadlinesByFairnessKey[key]
+		merged.merge(deadlines)
+		rs.res.DeadlinesByFairnessKey[key] = merged
+	}
+	for key, workerTime := range other.res.WorkerTimeByFairnessKey {
+		rs.res.WorkerTimeByFairnessKey[key] += workerTime
+	}
+	for path, count := range other.res.CountByFairnessPath {
+		rs.res.CountByFairnessPath[path] += count
+	}
+	for path, workerTime := range other.res.WorkerTimeByFairnessPath {
+		rs.res.WorkerTimeByFairnessPath[path] += workerTime
+	}
+	rs.demanded(other.fairnessWeights)
+	rs.all.merge(&other.all)
+	for p, histograms := range other.byPriority {
+		rs.priority(p).merge(histograms)
+	}
+	for key, histograms := range other.byFairnessKey {
+		rs.fairnessKey(key).merge(histograms)
+	}
+	for path, histograms := range other.byFairnessPath {
+		rs.fairnessPath(path).merge(histograms)
+	}
+}
+
+func (rs *resultsSummary) priority(p Priority) *latencyHistograms {
+	histograms, ok := rs.byPriority[p]
+	if !ok {
+		histograms = new(latencyHistograms)
+		rs.byPriority[p] = histograms
+	}
+	return histograms
+}
+
+func (rs *resultsSummary) fairnessKey(key string) *latencyHistograms {
+	histograms, ok := rs.byFairnessKey[key]
+	if !ok {
+		histograms = new(latencyHistograms)
+		rs.byFairnessKey[key] = histograms
+	}
+	return histograms
+}
+
+func (rs *resultsSummary) fairnessPath(path string) *latencyHistograms {
+	histograms, ok := rs.byFairnessPath[path]
+	if !ok {
+		histograms = new(latencyHistograms)
+		rs.byFairnessPath[path] = histograms
+	}
+	return histograms
+}
+
+func (rs *resultsSummary) results() SimulationResults {
+	for p, histograms := range rs.byPriority {
+		latencies := histograms.latencies(rs.percentiles)
+		rs.res.LatenciesByPriority[p] = latencies
+		rs.res.QueuedAvgByPriority[p] = latencies.Queued.Avg
+		rs.res.QueuedP50ByPriority[p] = histograms.queued.Percentile(50.0)
+		rs.res.QueuedP95ByPriority[p] = histograms.queued.Percentile(95.0)
+		rs.res.QueuedP99ByPriority[p] = histograms.queued.Percentile(99.0)
+		rs.res.QueuedQuantilesByPriority[p] = quantiles(&histograms.queued)
+	}
+	for key, histograms := range rs.byFairnessKey {
+		latencies := histograms.latencies(rs.percentiles)
+		rs.res.LatenciesByFairnessKey[key] = latencies
+		rs.res.QueuedAvgByFairnessKey[key] = latencies.Queued.Avg
+		rs.res.QueuedP50ByFairnessKey[key] = histograms.queued.Percentile(50.0)
+		rs.res.QueuedP95ByFairnessKey[key] = histograms.queued.Percentile(95.0)
+		rs.res.QueuedP99ByFairnessKey[key] = histograms.queued.Percentile(99.0)
+		rs.res.QueuedQuantilesByFairnessKey[key] = quantiles(&histograms.queued)
+	}
+	for path, histograms := range rs.byFairnessPath {
+		rs.res.LatenciesByFairnessPath[path] = histograms.latencies(rs.percentiles)
+	}
+	if rs.all.queued.Count() > 0 {
+		rs.res.Latencies = rs.all.latencies(rs.percentiles)
+		rs.res.QueuedAvg = rs.res.Latencies.Queued.Avg
+		rs.res.QueuedP50 = rs.all.queued.Percentile(50.0)
+		rs.res.QueuedP95 = rs.all.queued.Percentile(95.0)
+		rs.res.QueuedP99 = rs.all.queued.Percentile(99.0)
+	}
+	rs.res.Fairness = newFairnessResults(rs.res.CountByFairnessKey, rs.fairnessWeights)
+	return rs.res
+}
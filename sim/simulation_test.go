@@ -0,0 +1,365 @@
+package sim
+
+import (
+	"math/rand/v2"
+	"testing"
+	"time"
+)
+
+func Test_Simulation_Simulate_eventEngine(t *testing.T) {
+	s := &Simulation{
+		Config: SimulationConfig{
+			Engine:                   EngineEvent,
+			Duration:                 time.Minute,
+			ResultsBucketingInterval: 30 * time.Second,
+			TasksPerSecond:           100,
+			WorkerCount:              4,
+			WorkerTaskSlots:          10,
+		},
+		Clock:      NewSimulatedClock(time.Date(2024, 01, 01, 12, 00, 00, 00, time.UTC)),
+		RandSource: rand.NewPCG(123, 123),
+	}
+	if err := s.Init(); err != nil {
+		t.Errorf("expect no error, was %v", err)
+		t.FailNow()
+	}
+	res := s.Simulate()
+
+	if res.TasksProcessed == 0 {
+		t.Errorf("expect tasks to be processed")
+		t.Fail()
+	}
+	// 100 tasks per second at 100ms each needs ~10 of the 40 task slots,
+	// so with exact completions nothing should wait very long.
+	if res.QueuedP95 > 100*time.Millisecond {
+		t.Errorf("expect queued p95 to be under 100ms, was %v", res.QueuedP95)
+		t.Fail()
+	}
+	if res.ConcurrencyMaxByFairnessKey[""] > 40 {
+		t.Errorf("expect max concurrency to be at most the 40 task slots, was %d", res.ConcurrencyMaxByFairnessKey[""])
+		t.Fail()
+	}
+}
+
+func Test_Simulation_Simulate_rateLimited(t *testing.T) {
+	for _, engine := range []Engine{EngineTick, EngineEvent} {
+		clock := NewSimulatedClock(time.Date(2024, 01, 01, 12, 00, 00, 00, time.UTC))
+		s := &Simulation{
+			Config: SimulationConfig{
+				Engine:                   engine,
+				Duration:                 2 * time.Minute,
+				ResultsBucketingInterval: time.Minute,
+				TasksPerSecond:           100,
+				Tenants:                  []TenantProfile{{FairnessKey: "a", Fairness: 1, ArrivalWeight: 1}},
+				// arrivals stop after 10s, leaving a backlog that only the rate limit holds back.
+				Phases: []Phase{{Name: "idle", Start: 10 * time.Second, ArrivalMultipliers: map[string]float64{"a": 0}}},
+			},
+			Clock:      clock,
+			RandSource: rand.NewPCG(123, 123),
+			TaskQueue:  NewFeederTaskQueue(rand.New(rand.NewPCG(123, 123)), clock, CostModelTaskCount, map[string]Limit{"a": {Actions: 10, Quantum: time.Second}}),
+		}
+		if err := s.Init(); err != nil {
+			t.Errorf("expect no error, was %v", err)
+			t.FailNow()
+		}
+		res := s.Simulate()
+
+		// ~1000 tasks arrive, and 10 tasks per second for 2m drains most of them.
+		if res.TasksProcessed < 900 {
+			t.Errorf("%v: expect the rate limited backlog to be drained at 10 tasks per second, was %d tasks processed", engine, res.TasksProcessed)
+			t.Fail()
+		}
+	}
+}
+
+func Test_Simulation_Init_phaseConcurrencyLimits(t *testing.T) {
+	s := &Simulation{
+		Config: SimulationConfig{
+			Phases: []Phase{{Name: "limited", Start: time.Minute, ConcurrencyLimit: 10}},
+		},
+		// hides PullFiltered, such that the task queue cannot be concurrency limited.
+		TaskQueue: struct{ TaskQueue }{NewSimpleTaskQueue()},
+	}
+	if err := s.Init(); err == nil {
+		t.Errorf("expect an error for phase concurrency limits on a task queue that does not support them")
+		t.Fail()
+	}
+
+	s.TaskQueue = NewSimpleTaskQueue()
+	if err := s.Init(); err != nil {
+		t.Errorf("expect no error, was %v", err)
+		t.FailNow()
+	}
+	if _, ok := FindTaskQueue[ConcurrencyLimiter](s.TaskQueue); !ok {
+		t.Errorf("expect the task queue to be concurrency limited for the phases")
+		t.Fail()
+	}
+}
+
+func Test_Simulation_Simulate_phases(t *testing.T) {
+	for _, engine := range []Engine{EngineTick, EngineEvent} {
+		s := &Simulation{
+			Config: SimulationConfig{
+				Engine:                   engine,
+				Duration:                 3 * time.Minute,
+				ResultsBucketingInterval: 30 * time.Second,
+				ArrivalProcess:           PoissonArrivals{TasksPerSecond: 100},
+				// the tick engine frees task slots a tick late, so it
+				// needs ~2x the workers to keep up with the arrivals.
+				WorkerCount:     16,
+				WorkerTaskSlots: 10,
+				Tenants: []TenantProfile{
+					{FairnessKey: "a", Fairness: 1, ArrivalWeight: 1},
+					{FairnessKey: "b", Fairness: 1, ArrivalWeight: 1},
+				},
+				Phases: []Phase{
+					// "a" bursts to 10x while capacity drops to a single worker.
+					{Name: "burst", Start: time.Minute, ArrivalMultipliers: map[string]float64{"a": 10}, WorkerCount: 1},
+					{Name: "recovery", Start: 2 * time.Minute},
+				},
+			},
+			Clock:      NewSimulatedClock(time.Date(2024, 01, 01, 12, 00, 00, 00, time.UTC)),
+			RandSource: rand.NewPCG(123, 123),
+		}
+		if err := s.Init(); err != nil {
+			t.Errorf("expect no error, was %v", err)
+			t.FailNow()
+		}
+		res := s.Simulate()
+
+		if len(res.Phases) != 3 {
+			t.Errorf("%v: expect three phases (including the baseline), was %d", engine, len(res.Phases))
+			t.FailNow()
+		}
+		baseline, burst, recovery := res.Phases[0], res.Phases[1], res.Phases[2]
+		if baseline.Name != "baseline" || burst.Name != "burst" || recovery.Name != "recovery" {
+			t.Errorf("%v: expect phase names to be baseline, burst and recovery, was %q, %q and %q", engine, baseline.Name, burst.Name, recovery.Name)
+			t.Fail()
+		}
+		if burst.Start != time.Minute || burst.End != 2*time.Minute || recovery.End != 3*time.Minute {
+			t.Errorf("%v: expect burst to run from 1m to 2m and recovery to end at 3m, was %v-%v, %v", engine, burst.Start, burst.End, recovery.End)
+			t.Fail()
+		}
+		if baseline.QueueLength > 10 {
+			t.Errorf("%v: expect the queue to be (nearly) empty at the end of the baseline, was %d", engine, baseline.QueueLength)
+			t.Fail()
+		}
+		if burst.QueueLength < 10_000 {
+			t.Errorf("%v: expect a backlog at the end of the burst, was %d", engine, burst.QueueLength)
+			t.Fail()
+		}
+		var baselineArrivals, burstArrivals int
+		for _, count := range baseline.Results.CountByPriority {
+			baselineArrivals += count
+		}
+		for _, count := range burst.Results.CountByPriority {
+			burstArrivals += count
+		}
+		// 100 tasks per second, and then 500+50 tasks per second.
+		if burstArrivals < 5*baselineArrivals {
+			t.Errorf("%v: expect burst arrivals to be ~5.5x the baseline, was %d vs. %d", engine, burstArrivals, baselineArrivals)
+			t.Fail()
+		}
+		if burst.Results.QueuedAvg <= baseline.Results.QueuedAvg {
+			t.Errorf("%v: expect tasks arriving in the burst to queue longer than in the baseline", engine)
+			t.Fail()
+		}
+	}
+}
+
+func Test_Simulation_Simulate_buckets(t *testing.T) {
+	for _, engine := range []Engine{EngineTick, EngineEvent} {
+		s := &Simulation{
+			Config: SimulationConfig{
+				Engine:                   engine,
+				Duration:                 75 * time.Second,
+				ResultsBucketingInterval: 30 * time.Second,
+				ArrivalProcess:           PoissonArrivals{TasksPerSecond: 100},
+				WorkerCount:              16,
+				WorkerTaskSlots:          10,
+			},
+			Clock:      NewSimulatedClock(time.Date(2024, 01, 01, 12, 00, 00, 00, time.UTC)),
+			RandSource: rand.NewPCG(123, 123),
+		}
+		if err := s.Init(); err != nil {
+			t.Errorf("expect no error, was %v", err)
+			t.FailNow()
+		}
+		res := s.Simulate()
+
+		// two full buckets and the last partial bucket.
+		if len(res.Buckets) != 3 {
+			t.Errorf("%v: expect three buckets, was %d", engine, len(res.Buckets))
+			t.FailNow()
+		}
+		if res.Buckets[0].Start != 0 || res.Buckets[0].End != 30*time.Second || res.Buckets[2].End != 75*time.Second {
+			t.Errorf("%v: expect buckets to span 0s-30s through to 75s, was %v-%v through to %v", engine, res.Buckets[0].Start, res.Buckets[0].End, res.Buckets[2].End)
+			t.Fail()
+		}
+		var tasksProcessed int
+		for _, bucket := range res.Buckets {
+			tasksProcessed += bucket.Results.TasksProcessed
+			if bucket.Throughput < 90 || bucket.Throughput > 110 {
+				t.Errorf("%v: expect bucket throughput to be ~100 tasks per second, was %.1f", engine, bucket.Throughput)
+				t.Fail()
+			}
+			if bucket.Results.QueuedP50 > bucket.Results.QueuedP95 || bucket.Results.QueuedP95 > bucket.Results.QueuedP99 {
+				t.Errorf("%v: expect bucket percentiles to be ordered, was %v, %v and %v", engine, bucket.Results.QueuedP50, bucket.Results.QueuedP95, bucket.Results.QueuedP99)
+				t.Fail()
+			}
+		}
+		if tasksProcessed != res.TasksProcessed {
+			t.Errorf("%v: expect bucket tasks processed to add up to %d, was %d", engine, res.TasksProcessed, tasksProcessed)
+			t.Fail()
+		}
+	}
+}
+
+func Test_Simulation_Simulate_latencies(t *testing.T) {
+	s := &Simulation{
+		Config: SimulationConfig{
+			Engine:          EngineEvent,
+			Duration:        time.Minute,
+			ArrivalProcess:  PoissonArrivals{TasksPerSecond: 100},
+			TaskDuration:    ExponentialDuration{Mean: 200 * time.Millisecond},
+			WorkerCount:     1,
+			WorkerTaskSlots: 25,
+			Percentiles:     []float64{50, 99, 99.9},
+		},
+		Clock:      NewSimulatedClock(time.Date(2024, 01, 01, 12, 00, 00, 00, time.UTC)),
+		RandSource: rand.NewPCG(123, 123),
+	}
+	if err := s.Init(); err != nil {
+		t.Errorf("expect no error, was %v", err)
+		t.FailNow()
+	}
+	res := s.Simulate()
+
+	latencies := res.Latencies
+	if latencies.Service.Count != res.TasksProcessed || latencies.EndToEnd.Count != res.TasksProcessed {
+		t.Errorf("expect service and end-to-end latencies of the %d tasks processed, was %d and %d", res.TasksProcessed, latencies.Service.Count, latencies.EndToEnd.Count)
+		t.Fail()
+	}
+	if latencies.Service.Avg < 180*time.Millisecond || latencies.Service.Avg > 220*time.Millisecond {
+		t.Errorf("expect the service time to average ~200ms, was %v", latencies.Service.Avg)
+		t.Fail()
+	}
+	if len(latencies.EndToEnd.Percentiles) != 3 {
+		t.Errorf("expect the configured percentiles, was %v", latencies.EndToEnd.Percentiles)
+		t.FailNow()
+	}
+	p50, _ := latencies.EndToEnd.Percentile(50)
+	p999, _ := latencies.EndToEnd.Percentile(99.9)
+	if p50 > p999 || p999 > latencies.EndToEnd.Max {
+		t.Errorf("expect end-to-end percentiles to be ordered, was %v, %v and max %v", p50, p999, latencies.EndToEnd.Max)
+		t.Fail()
+	}
+	// at 80% utilization tasks queue, so end-to-end is more than the service time alone.
+	if latencies.EndToEnd.Avg <= latencies.Service.Avg || latencies.EndToEnd.Avg < latencies.Queued.Avg {
+		t.Errorf("expect end-to-end latency to include queueing, was %v vs. %v service and %v queued", latencies.EndToEnd.Avg, latencies.Service.Avg, latencies.Queued.Avg)
+		t.Fail()
+	}
+	if _, ok := res.LatenciesByPriority[P2]; !ok {
+		t.Errorf("expect latencies by priority")
+		t.Fail()
+	}
+}
+
+func Test_Simulation_Simulate_deadlines(t *testing.T) {
+	missRates := make(map[string]float64)
+	for _, queueType := range []string{"simple", "edf"} {
+		r := rand.NewPCG(123, 123)
+		tq, err := NewTaskQueue(TaskQueueOptions{Type: queueType}, rand.New(r), nil)
+		if err != nil {
+			t.Errorf("expect new task queue error to be nil, was %v", err)
+			t.FailNow()
+		}
+		s := &Simulation{
+			Config: SimulationConfig{
+				Engine:             EngineEvent,
+				Duration:           time.Minute,
+				ArrivalProcess:     PoissonArrivals{TasksPerSecond: 100},
+				TaskDuration:       ExponentialDuration{Mean: 200 * time.Millisecond},
+				WorkerCount:        1,
+				WorkerTaskSlots:    15,
+				PriorityWeights:    map[Priority]int{P1: 1, P3: 3},
+				DeadlineByPriority: map[Priority]time.Duration{P1: time.Second},
+			},
+			Clock:      NewSimulatedClock(time.Date(2024, 01, 01, 12, 00, 00, 00, time.UTC)),
+			RandSource: r,
+			TaskQueue:  tq,
+		}
+		if err := s.Init(); err != nil {
+			t.Errorf("expect no error, was %v", err)
+			t.FailNow()
+		}
+		res := s.Simulate()
+
+		if _, ok := res.DeadlinesByPriority[P3]; ok || res.Deadlines != res.DeadlinesByPriority[P1] {
+			t.Errorf("%s: expect only P1 tasks to have a deadline, was %v", queueType, res.DeadlinesByPriority)
+			t.Fail()
+		}
+		if res.Deadlines.Tasks == 0 || res.Deadlines.Tasks > res.CountByPriority[P1] {
+			t.Errorf("%s: expect at most the %d P1 tasks to have a deadline, was %d", queueType, res.CountByPriority[P1], res.Deadlines.Tasks)
+			t.Fail()
+		}
+		missRates[queueType] = res.Deadlines.MissRate()
+	}
+	// the queue is overloaded, so tasks served in arrival order miss their deadline,
+	// while serving them earliest deadline first meets it.
+	if missRates["simple"] < 0.5 || missRates["edf"] > 0.01 {
+		t.Errorf("expect most P1 tasks to miss their deadline with a simple queue but not edf, was %v", missRates)
+		t.Fail()
+	}
+}
+
+func Test_Simulation_Simulate_fairnessPaths(t *testing.T) {
+	s := &Simulation{
+		Config: SimulationConfig{
+			Engine:                   EngineEvent,
+			Duration:                 time.Minute,
+			ResultsBucketingInterval: 30 * time.Second,
+			ArrivalProcess:           PoissonArrivals{TasksPerSecond: 100},
+			TaskDuration:             ExponentialDuration{Mean: 200 * time.Millisecond},
+			WorkerCount:              1,
+			WorkerTaskSlots:          10,
+			Tenants: []TenantProfile{
+				{FairnessKey: "acct1/merchantA/export", ArrivalWeight: 1},
+				{FairnessKey: "acct1/merchantA/sync", ArrivalWeight: 1},
+				{FairnessKey: "acct1/merchantB/export", ArrivalWeight: 1},
+				{FairnessKey: "acct2/merchantA/export", ArrivalWeight: 1},
+			},
+		},
+		Clock:      NewSimulatedClock(time.Date(2024, 01, 01, 12, 00, 00, 00, time.UTC)),
+		RandSource: rand.NewPCG(123, 123),
+		TaskQueue:  NewHierarchicalFairTaskQueue(CostModelTaskCount, nil),
+	}
+	if err := s.Init(); err != nil {
+		t.Errorf("expect no error, was %v", err)
+		t.FailNow()
+	}
+	res := s.Simulate()
+
+	acct1 := res.CountByFairnessKey["acct1/merchantA/export"] + res.CountByFairnessKey["acct1/merchantA/sync"] + res.CountByFairnessKey["acct1/merchantB/export"]
+	if res.CountByFairnessPath["acct1"] != acct1 || res.CountByFairnessPath["acct2"] != res.CountByFairnessKey["acct2/merchantA/export"] {
+		t.Errorf("expect paths to count the tasks of the fairness keys under them, was %v", res.CountByFairnessPath)
+		t.Fail()
+	}
+	if len(res.CountByFairnessPath) != 5 || res.LatenciesByFairnessPath["acct1/merchantA"].EndToEnd.Count != res.CountByFairnessPath["acct1/merchantA"] {
+		t.Errorf("expect results for acct1, acct1/merchantA, acct1/merchantB, acct2 and acct2/merchantA, was %v", res.CountByFairnessPath)
+		t.Fail()
+	}
+	// the queue is overloaded, so acct2 is served about as many tasks as the three keys of acct1.
+	if share := res.Fairness.ShareByFairnessPath["acct2"]; share < 0.45 || share > 0.55 {
+		t.Errorf("expect acct2 to be served ~50%% of the tasks, was %.1f%%", 100*share)
+		t.Fail()
+	}
+	var bucketCount int
+	for _, bucket := range res.Buckets {
+		bucketCount += bucket.Results.CountByFairnessPath["acct1"]
+	}
+	if bucketCount != res.CountByFairnessPath["acct1"] {
+		t.Errorf("expect bucket path counts to add up to %d, was %d", res.CountByFairnessPath["acct1"], bucketCount)
+		t.Fail()
+	}
+}
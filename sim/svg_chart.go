@@ -0,0 +1,159 @@
+package sim
+
+import (
+	"fmt"
+	"html"
+	"html/template"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// chartColors is a categorical palette; series beyond its length reuse colors.
+var chartColors = []string{
+	"#4e79a7", "#f28e2b", "#e15759", "#76b7b2", "#59a14f",
+	"#edc948", "#b07aa1", "#ff9da7", "#9c755f", "#bab0ac",
+}
+
+func chartColor(index int) string {
+	return chartColors[index%len(chartColors)]
+}
+
+type chartPoint struct {
+	X, Y float64
+}
+
+// chartSeries is a named series of points in a chart.
+type chartSeries struct {
+	Name   string
+	Color  string
+	Points []chartPoint
+}
+
+// svgChart renders one or more series as an inline svg line (or bar) chart.
+type svgChart struct {
+	XLabel string
+	YLabel string
+	// XMax, if set, clips the x axis, e.g. to hide the long tail of a distribution.
+	XMax float64
+	// Bars renders the points of the first series as bars centered on their x values.
+	Bars   bool
+	Series []chartSeries
+}
+
+const (
+	chartWidth        = 720
+	chartHeight       = 300
+	chartMarginLeft   = 72
+	chartMarginRight  = 16
+	chartMarginTop    = 12
+	chartMarginBottom = 44
+	chartTicks        = 5
+	// chartMaxPoints is the most points drawn per series; longer series are thinned.
+	chartMaxPoints = 1000
+)
+
+// SVG returns the chart as svg markup.
+func (c svgChart) SVG() template.HTML {
+	xMax, yMax := c.XMax, 0.0
+	for _, series := range c.Series {
+		for _, p := range series.Points {
+			if c.XMax == 0 {
+				xMax = max(xMax, p.X)
+			}
+			if p.X <= xMax {
+				yMax = max(yMax, p.Y)
+			}
+		}
+	}
+	if xMax <= 0 {
+		xMax = 1
+	}
+	if yMax <= 0 {
+		yMax = 1
+	}
+	xMax, yMax = niceCeil(xMax), niceCeil(yMax)
+
+	plotWidth := float64(chartWidth - chartMarginLeft - chartMarginRight)
+	plotHeight := float64(chartHeight - chartMarginTop - chartMarginBottom)
+	x := func(v float64) float64 { return chartMarginLeft + plotWidth*min(v, xMax)/xMax }
+	y := func(v float64) float64 { return chartMarginTop + plotHeight*(1-v/yMax) }
+
+	var output strings.Builder
+	fmt.Fprintf(&output, `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 %d %d" width="%d" height="%d" font-family="sans-serif" font-size="11">`, chartWidth, chartHeight, chartWidth, chartHeight)
+	for tick := 0; tick <= chartTicks; tick++ {
+		xValue := xMax * float64(tick) / chartTicks
+		yValue := yMax * float64(tick) / chartTicks
+		fmt.Fprintf(&output, `<line x1="%.1f" y1="%d" x2="%.1f" y2="%d" stroke="#eee"/>`, x(xValue), chartMarginTop, x(xValue), chartHeight-chartMarginBottom)
+		fmt.Fprintf(&output, `<text x="%.1f" y="%d" text-anchor="middle">%s</text>`, x(xValue), chartHeight-chartMarginBottom+14, formatChartValue(xValue))
+		fmt.Fprintf(&output, `<line x1="%d" y1="%.1f" x2="%d" y2="%.1f" stroke="#eee"/>`, chartMarginLeft, y(yValue), chartWidth-chartMarginRight, y(yValue))
+		fmt.Fprintf(&output, `<text x="%d" y="%.1f" text-anchor="end" dominant-baseline="middle">%s</text>`, chartMarginLeft-6, y(yValue), formatChartValue(yValue))
+	}
+	fmt.Fprintf(&output, `<line x1="%d" y1="%d" x2="%d" y2="%d" stroke="#333"/>`, chartMarginLeft, chartHeight-chartMarginBottom, chartWidth-chartMarginRight, chartHeight-chartMarginBottom)
+	fmt.Fprintf(&output, `<line x1="%d" y1="%d" x2="%d" y2="%d" stroke="#333"/>`, chartMarginLeft, chartMarginTop, chartMarginLeft, chartHeight-chartMarginBottom)
+	fmt.Fprintf(&output, `<text x="%.1f" y="%d" text-anchor="middle">%s</text>`, chartMarginLeft+plotWidth/2, chartHeight-8, html.EscapeString(c.XLabel))
+	fmt.Fprintf(&output, `<text transform="translate(14 %.1f) rotate(-90)" text-anchor="middle">%s</text>`, chartMarginTop+plotHeight/2, html.EscapeString(c.YLabel))
+
+	for index, series := range c.Series {
+		if c.Bars && index == 0 {
+			barWidth := plotWidth / float64(max(len(series.Points), 1)) * 0.8
+			for _, p := range series.Points {
+				fmt.Fprintf(&output, `<rect x="%.1f" y="%.1f" width="%.1f" height="%.1f" fill="%s"><title>%s</title></rect>`,
+					x(p.X)-barWidth/2, y(p.Y), barWidth, y(0)-y(p.Y), series.Color, html.EscapeString(series.Name+": "+formatChartValue(p.Y)))
+			}
+			continue
+		}
+		var path strings.Builder
+		for pointIndex, p := range thinPoints(series.Points, chartMaxPoints) {
+			if p.X > xMax {
+				break
+			}
+			command := "L"
+			if pointIndex == 0 {
+				command = "M"
+			}
+			fmt.Fprintf(&path, "%s%.1f %.1f", command, x(p.X), y(p.Y))
+		}
+		fmt.Fprintf(&output, `<path d="%s" fill="none" stroke="%s" stroke-width="1.5"><title>%s</title></path>`, path.String(), series.Color, html.EscapeString(series.Name))
+	}
+	output.WriteString(`</svg>`)
+	return template.HTML(output.String())
+}
+
+// thinPoints returns at most about a given number of evenly spaced points, always keeping the last point.
+func thinPoints(points []chartPoint, most int) []chartPoint {
+	if len(points) <= most {
+		return points
+	}
+	stride := (len(points) + most - 1) / most
+	output := make([]chartPoint, 0, most+1)
+	for index := 0; index < len(points); index += stride {
+		output = append(output, points[index])
+	}
+	if last := points[len(points)-1]; output[len(output)-1] != last {
+		output = append(output, last)
+	}
+	return output
+}
+
+// niceCeil rounds a positive value up to 1, 2, 2.5 or 5 times a power of ten.
+func niceCeil(value float64) float64 {
+	magnitude := math.Pow(10, math.Floor(math.Log10(value)))
+	for _, step := range []float64{1, 2, 2.5, 5, 10} {
+		if value <= step*magnitude {
+			return step * magnitude
+		}
+	}
+	return 10 * magnitude
+}
+
+func formatChartValue(value float64) string {
+	switch {
+	case value >= 1e6:
+		return strconv.FormatFloat(value/1e6, 'g', 3, 64) + "M"
+	case value >= 1e3:
+		return strconv.FormatFloat(value/1e3, 'g', 3, 64) + "k"
+	default:
+		return strconv.FormatFloat(value, 'g', 3, 64)
+	}
+}
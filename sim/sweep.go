@@ -0,0 +1,235 @@
+package sim
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SweepParameters are the scenario parameters that can be swept.
+//
+// "tasksPerSecond" sets the mean rate of the shared arrival process and scales every other
+// arrival rate of the scenario (e.g. of tenants and phases) in proportion, such that the mix
+// of the workload is kept.
+var SweepParameters = []string{"tasksPerSecond", "workerCount", "workerTaskSlots", "concurrencyLimit", "queueType"}
+
+// SweepParameter is a scenario parameter and the values to sweep it over.
+type SweepParameter struct {
+	Name   string
+	Values []string
+}
+
+// ParseSweepParameter parses a parameter to sweep from its name and either a list of
+// values, e.g. "workerCount=16,32,64", or an inclusive range with an optional step
+// (defaulting to 1), e.g. "tasksPerSecond=2000..4000:200".
+func ParseSweepParameter(value string) (param SweepParameter, err error) {
+	name, values, ok := strings.Cut(value, "=")
+	if !ok || values == "" {
+		err = fmt.Errorf("invalid sweep parameter %q: must be of the form name=values", value)
+		return
+	}
+	for _, known := range SweepParameters {
+		if strings.EqualFold(name, known) {
+			param.Name = known
+		}
+	}
+	if param.Name == "" {
+		err = fmt.Errorf("invalid sweep parameter %q: must be one of %s", name, strings.Join(SweepParameters, ", "))
+		return
+	}
+
+	from, to, isRange := strings.Cut(values, "..")
+	if !isRange {
+		for _, v := range strings.Split(values, ",") {
+			param.Values = append(param.Values, strings.TrimSpace(v))
+		}
+		return
+	}
+	to, stepValue, hasStep := strings.Cut(to, ":")
+	if !hasStep {
+		stepValue = "1"
+	}
+	start, startErr := strconv.ParseFloat(from, 64)
+	end, endErr := strconv.ParseFloat(to, 64)
+	step, stepErr := strconv.ParseFloat(stepValue, 64)
+	if startErr != nil || endErr != nil || stepErr != nil || step <= 0 || end < start {
+		err = fmt.Errorf("invalid sweep range %q: must be of the form from..to or from..to:step, with from <= to and a positive step", values)
+		return
+	}
+	// the step count is rounded such that e.g. 0.1 steps don't drop the end of the range.
+	steps := int(math.Floor((end-start)/step + 1e-9))
+	for index := 0; index <= steps; index++ {
+		param.Values = append(param.Values, strconv.FormatFloat(start+float64(index)*step, 'f', -1, 64))
+	}
+	return
+}
+
+// WithParameter returns a copy of the scenario with a parameter of [SweepParameters] set.
+func (sc Scenario) WithParameter(name, value string) (Scenario, error) {
+	if !slices.Contains(SweepParameters, name) {
+		return sc, fmt.Errorf("invalid sweep parameter %q: must be one of %s", name, strings.Join(SweepParameters, ", "))
+	}
+	if name == "queueType" {
+		sc.Queue.Type = value
+		if value != "feeder" {
+			sc.Queue.RateLimits = nil
+		}
+		return sc, nil
+	}
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return sc, fmt.Errorf("invalid %s: %q is not a number", name, value)
+	}
+	// the numeric parameters are all whole numbers.
+	if number != math.Trunc(number) {
+		return sc, fmt.Errorf("invalid %s: %q is not a whole number", name, value)
+	}
+	switch name {
+	case "tasksPerSecond":
+		base := sc.tasksPerSecond()
+		if base <= 0 {
+			return sc, fmt.Errorf("invalid %s: the scenario has no arrival rate to scale", name)
+		}
+		sc = sc.scaleArrivals(number / base)
+		sc.TasksPerSecond = int(number)
+	case "workerCount":
+		sc.WorkerCount = int(number)
+	case "workerTaskSlots":
+		sc.WorkerTaskSlots = int(number)
+	case "concurrencyLimit":
+		sc.Queue.ConcurrencyLimit = int(number)
+	}
+	return sc, nil
+}
+
+// tasksPerSecond returns the mean rate of the scenario's shared arrival process.
+func (sc Scenario) tasksPerSecond() float64 {
+	switch {
+	case sc.Arrivals == nil:
+		return float64(SimulationConfig{TasksPerSecond: sc.TasksPerSecond}.TasksPerSecondOrDefault())
+	case sc.Arrivals.Type == "bursty":
+		on, _ := time.ParseDuration(sc.Arrivals.MeanOnDuration)
+		off, _ := time.ParseDuration(sc.Arrivals.MeanOffDuration)
+		if on+off <= 0 {
+			return (sc.Arrivals.OnTasksPerSecond + sc.Arrivals.OffTasksPerSecond) / 2
+		}
+		return (sc.Arrivals.OnTasksPerSecond*on.Seconds() + sc.Arrivals.OffTasksPerSecond*off.Seconds()) / (on + off).Seconds()
+	default:
+		return sc.Arrivals.TasksPerSecond
+	}
+}
+
+// scaleArrivals returns a copy of the scenario with every arrival rate multiplied by a factor.
+func (sc Scenario) scaleArrivals(factor float64) Scenario {
+	sc.Arrivals = sc.Arrivals.scaled(factor)
+	tenants := make([]TenantSpec, len(sc.Tenants))
+	for index, tenant := range sc.Tenants {
+		tenant.Arrivals = tenant.Arrivals.scaled(factor)
+		tenants[index] = tenant
+	}
+	sc.Tenants = tenants
+	phases := make([]PhaseSpec, len(sc.Phases))
+	for index, phase := range sc.Phases {
+		phase.Arrivals = phase.Arrivals.scaled(factor)
+		phaseTenants := make([]TenantSpec, len(phase.Tenants))
+		for tenantIndex, tenant := range phase.Tenants {
+			tenant.Arrivals = tenant.Arrivals.scaled(factor)
+			phaseTenants[tenantIndex] = tenant
+		}
+		phase.Tenants = phaseTenants
+		phases[index] = phase
+	}
+	sc.Phases = phases
+	return sc
+}
+
+func (as *ArrivalSpec) scaled(factor float64) *ArrivalSpec {
+	if as == nil {
+		return nil
+	}
+	output := *as
+	output.TasksPerSecond *= factor
+	output.OnTasksPerSecond *= factor
+	output.OffTasksPerSecond *= factor
+	return &output
+}
+
+// SweepResults are the results of a parameter sweep.
+type SweepResults struct {
+	Parameters []SweepParameter
+	Points     []SweepPoint
+}
+
+// SweepPoint is a point of a parameter sweep grid and the results of simulating it.
+type SweepPoint struct {
+	// Values are the values of the swept parameters, in the order of the parameters.
+	Values  []string
+	Results SimulationResults
+}
+
+// Sweep runs the scenario at each point of the grid formed by the parameters, in parallel
+// across at most the given number of goroutines (or GOMAXPROCS if not positive).
+//
+// Every point is run with the scenario's seed (or the same random seed if unset), such that
+// differences between points are due to the parameters rather than to chance. Points are
+// returned in row-major order, i.e. the values of the last parameter vary fastest.
+func Sweep(sc Scenario, params []SweepParameter, parallelism int) (output SweepResults, err error) {
+	output.Parameters = params
+	if sc.Seed == nil {
+		seed := rand.Uint64()
+		sc.Seed = &seed
+	}
+	var scenarios []Scenario
+	grid := [][]string{nil}
+	for _, param := range params {
+		var next [][]string
+		for _, values := range grid {
+			for _, value := range param.Values {
+				next = append(next, append(values[:len(values):len(values)], value))
+			}
+		}
+		grid = next
+	}
+	for _, values := range grid {
+		point := sc
+		for index, value := range values {
+			if point, err = point.WithParameter(params[index].Name, value); err != nil {
+				return
+			}
+		}
+		if err = point.Validate(); err != nil {
+			err = fmt.Errorf("%s: %w", describeSweepPoint(params, values), err)
+			return
+		}
+		scenarios = append(scenarios, point)
+		output.Points = append(output.Points, SweepPoint{Values: values})
+	}
+
+	errs := make([]error, len(scenarios))
+	parallelFor(len(scenarios), parallelism, func(index int) {
+		s, err := scenarios[index].NewSimulation(nil)
+		if err != nil {
+			errs[index] = err
+			return
+		}
+		output.Points[index].Results = s.Simulate()
+	})
+	for _, err = range errs {
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+func describeSweepPoint(params []SweepParameter, values []string) string {
+	parts := make([]string, len(values))
+	for index, value := range values {
+		parts[index] = params[index].Name + "=" + value
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,99 @@
+package sim
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func Test_ParseSweepParameter(t *testing.T) {
+	for _, c := range []struct {
+		value    string
+		name     string
+		values   []string
+		expected bool
+	}{
+		{value: "TasksPerSecond=2000..3000:500", name: "tasksPerSecond", values: []string{"2000", "2500", "3000"}, expected: true},
+		{value: "workerCount=16,32, 64", name: "workerCount", values: []string{"16", "32", "64"}, expected: true},
+		{value: "workerTaskSlots=1..3", name: "workerTaskSlots", values: []string{"1", "2", "3"}, expected: true},
+		{value: "queueType=simple,drr", name: "queueType", values: []string{"simple", "drr"}, expected: true},
+		{value: "workerCount"},
+		{value: "tickInterval=1s"},
+		{value: "workerCount=4..2"},
+		{value: "workerCount=2..4:0"},
+	} {
+		param, err := ParseSweepParameter(c.value)
+		if (err == nil) != c.expected {
+			t.Errorf("%s: expect ok to be %v, was error %v", c.value, c.expected, err)
+			t.Fail()
+			continue
+		}
+		if c.expected && (param.Name != c.name || !slices.Equal(param.Values, c.values)) {
+			t.Errorf("%s: expect %s over %v, was %s over %v", c.value, c.name, c.values, param.Name, param.Values)
+			t.Fail()
+		}
+	}
+}
+
+func Test_Scenario_WithParameter_tasksPerSecond(t *testing.T) {
+	scenario := Scenario{
+		Arrivals: &ArrivalSpec{Type: "poisson", TasksPerSecond: 100},
+		Tenants: []TenantSpec{
+			{FairnessKey: "shared", ArrivalWeight: 1},
+			{FairnessKey: "own", Arrivals: &ArrivalSpec{Type: "constant", TasksPerSecond: 50}},
+		},
+	}
+	scaled, err := scenario.WithParameter("tasksPerSecond", "300")
+	if err != nil {
+		t.Errorf("expect error to be nil, was %v", err)
+		t.FailNow()
+	}
+	if scaled.Arrivals.TasksPerSecond != 300 || scaled.Tenants[1].Arrivals.TasksPerSecond != 150 {
+		t.Errorf("expect arrival rates to be tripled, was %v and %v", scaled.Arrivals.TasksPerSecond, scaled.Tenants[1].Arrivals.TasksPerSecond)
+		t.Fail()
+	}
+	if scenario.Arrivals.TasksPerSecond != 100 || scenario.Tenants[1].Arrivals.TasksPerSecond != 50 {
+		t.Errorf("expect the original scenario to be unchanged")
+		t.Fail()
+	}
+}
+
+func Test_Scenario_WithParameter_wholeNumbers(t *testing.T) {
+	scenario := Scenario{Arrivals: &ArrivalSpec{Type: "poisson", TasksPerSecond: 100}}
+	for _, name := range []string{"tasksPerSecond", "workerCount", "workerTaskSlots", "concurrencyLimit"} {
+		_, err := scenario.WithParameter(name, "2.5")
+		if err == nil || !strings.Contains(err.Error(), name) {
+			t.Errorf("expect an error naming %s for a fractional value, was %v", name, err)
+			t.Fail()
+		}
+	}
+}
+
+func Test_Sweep(t *testing.T) {
+	seed := uint64(1)
+	scenario := Scenario{
+		Seed:        &seed,
+		Engine:      "event",
+		Duration:    "30s",
+		WorkerCount: 1,
+		Arrivals:    &ArrivalSpec{Type: "poisson", TasksPerSecond: 100},
+		Queue:       QueueSpec{Type: "simple"},
+	}
+	res, err := Sweep(scenario, []SweepParameter{
+		{Name: "tasksPerSecond", Values: []string{"100", "200"}},
+		{Name: "workerCount", Values: []string{"1", "2", "4"}},
+	}, 2)
+	if err != nil {
+		t.Errorf("expect sweep error to be nil, was %v", err)
+		t.FailNow()
+	}
+	if len(res.Points) != 6 || !slices.Equal(res.Points[4].Values, []string{"200", "2"}) {
+		t.Errorf("expect six points with the last parameter varying fastest, was %v", res.Points)
+		t.FailNow()
+	}
+	// twice the arrivals for the same seed.
+	if low, high := res.Points[0].Results.TasksProcessed, res.Points[3].Results.TasksProcessed; high < 2*low*9/10 {
+		t.Errorf("expect ~twice the tasks at 200 tasks per second, was %d vs. %d", high, low)
+		t.Fail()
+	}
+}
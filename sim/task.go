@@ -13,6 +13,10 @@ type Task struct {
 	DispatchedUTC time.Time
 	CompletedUTC  time.Time
 	WorkDuration  time.Duration
+	// Deadline, if set, is the time the task should be dispatched by.
+	Deadline time.Time
+	// Boost is how many priorities an [AgingPolicy] had raised the task by when it was dispatched.
+	Boost int
 }
 
 func (t Task) Key() UUID {
@@ -5,3 +5,69 @@ type TaskQueue interface {
 	Pull() (*Task, bool)
 	Len() int
 }
+
+// FilteredTaskQueue is a task queue that can pull the next task of only some fairness keys,
+// e.g. to skip the fairness keys at a limit without dispatching their tasks.
+type FilteredTaskQueue interface {
+	TaskQueue
+	// PullFiltered pulls the next task of the fairness keys allow returns true for,
+	// as if the tasks of the other fairness keys were not queued.
+	PullFiltered(allow func(fairnessKey string) bool) (*Task, bool)
+}
+
+// allowAllFairnessKeys is the filter of an unfiltered pull.
+func allowAllFairnessKeys(string) bool {
+	return true
+}
+
+// CompletionObserver is an optional interface for task queues
+// that want to be told when a task they dispatched has completed.
+//
+// The simulation calls OnComplete for every finished task after its
+// CompletedUTC has been set, such that the queue can observe
+// how long the task actually held a worker task slot.
+type CompletionObserver interface {
+	OnComplete(*Task)
+}
+
+// Drainer is an optional interface for task queues that can refuse to pull
+// queued tasks (e.g. because of limits) and so must be drained explicitly.
+type Drainer interface {
+	// Drain removes and returns every queued task, ignoring any limits.
+	Drain() []*Task
+}
+
+// DrainTaskQueue removes and returns every task remaining in a task queue.
+func DrainTaskQueue(q TaskQueue) (output []*Task) {
+	if drainer, ok := q.(Drainer); ok {
+		return drainer.Drain()
+	}
+	for q.Len() > 0 {
+		t, ok := q.Pull()
+		if !ok {
+			return
+		}
+		output = append(output, t)
+	}
+	return
+}
+
+// Wrapper is an optional interface for task queues that wrap another task queue.
+type Wrapper interface {
+	Unwrap() TaskQueue
+}
+
+// FindTaskQueue returns the outermost task queue in a chain of wrapped task queues that implements T.
+func FindTaskQueue[T any](q TaskQueue) (found T, ok bool) {
+	for q != nil {
+		if found, ok = q.(T); ok {
+			return
+		}
+		wrapper, isWrapper := q.(Wrapper)
+		if !isWrapper {
+			return
+		}
+		q = wrapper.Unwrap()
+	}
+	return
+}
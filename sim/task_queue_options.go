@@ -0,0 +1,66 @@
+package sim
+
+import (
+	"fmt"
+	"math/rand/v2"
+)
+
+// TaskQueueTypes are the task queue types [NewTaskQueue] can construct.
+var TaskQueueTypes = []string{"simple", "priority", "fairness", "feeder", "drr", "wfq", "edf", "hfq"}
+
+// TaskQueueOptions are the parameters used to construct a task queue by type.
+type TaskQueueOptions struct {
+	// Type is one of [TaskQueueTypes].
+	Type string
+	// CostModel is how the fair task queues charge fairness keys for tasks.
+	CostModel CostModel
+	// RateLimits are the rate limits by fairness key for the "feeder" task queue.
+	RateLimits map[string]Limit
+	// ConcurrencyLimit, if set, wraps the task queue such that at most this many
+	// tasks per fairness key are held by workers at once.
+	ConcurrencyLimit int
+	// ConcurrencyLimits overrides the concurrency limit for specific fairness keys.
+	ConcurrencyLimits map[string]int
+	// Aging raises the priority of tasks as they wait in the "priority" and "fairness" task queues.
+	Aging AgingPolicy
+	// PriorityClassWeights, if set, has the "fairness" task queue share dispatches between
+	// priorities in proportion to these weights rather than serve them strictly by priority.
+	PriorityClassWeights map[Priority]int
+	// PathWeights are the weights of the paths of hierarchical fairness keys (e.g. "acct1" or
+	// "acct1/merchantA") for the "hfq" task queue.
+	PathWeights map[string]float64
+}
+
+// NewTaskQueue returns a new task queue for a given set of options.
+func NewTaskQueue(opts TaskQueueOptions, r *rand.Rand, c Clock) (tq TaskQueue, err error) {
+	switch opts.Type {
+	case "simple":
+		tq = NewSimpleTaskQueue()
+	case "priority":
+		tq = NewPrioritySortedTaskQueue(c, opts.Aging)
+	case "fairness":
+		tq = NewPriorityFairnessTaskQueue(r, c, opts.CostModel, opts.Aging, opts.PriorityClassWeights)
+	case "feeder":
+		tq = NewFeederTaskQueue(r, c, opts.CostModel, opts.RateLimits)
+	case "drr":
+		tq = NewDeficitRoundRobinTaskQueue(opts.CostModel)
+	case "wfq":
+		tq = NewWeightedFairTaskQueue(opts.CostModel)
+	case "edf":
+		tq = NewEarliestDeadlineTaskQueue(r)
+	case "hfq":
+		tq = NewHierarchicalFairTaskQueue(opts.CostModel, opts.PathWeights)
+	default:
+		err = fmt.Errorf("invalid queue type: %q", opts.Type)
+		return
+	}
+	if opts.ConcurrencyLimit > 0 || len(opts.ConcurrencyLimits) > 0 {
+		inner, ok := tq.(FilteredTaskQueue)
+		if !ok {
+			err = fmt.Errorf("queue type %q does not support concurrency limits", opts.Type)
+			return
+		}
+		tq = NewConcurrencyLimitedTaskQueue(inner, opts.ConcurrencyLimit, opts.ConcurrencyLimits)
+	}
+	return
+}
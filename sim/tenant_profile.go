@@ -0,0 +1,53 @@
+package sim
+
+import "sort"
+
+// TenantProfile describes the workload of a single tenant, i.e. of a single fairness key.
+type TenantProfile struct {
+	FairnessKey string
+	// Fairness is the fairness weight of the tenant's tasks.
+	Fairness float64
+	// ArrivalWeight is the tenant's share of the tasks produced by the simulation's arrival
+	// process, relative to the other tenants sharing it.
+	ArrivalWeight int
+	// Arrivals is the tenant's own arrival process; tenants with their own arrival
+	// process do not take a share of the simulation's arrival process.
+	Arrivals ArrivalProcess
+	// PriorityWeights is the tenant's priority mix, and defaults to the simulation's priority weights.
+	PriorityWeights map[Priority]int
+	// TaskDuration is the distribution of the tenant's task work durations, and defaults
+	// to the simulation's task duration distribution for each task's priority.
+	//
+	// Note that it takes precedence over the simulation's task duration by priority overrides.
+	TaskDuration DurationDistribution
+}
+
+// TenantsOrDefault returns the tenant profiles, translating the flat fairness
+// key, fairness and task duration by fairness key maps into profiles if no
+// tenants are set explicitly.
+func (sc SimulationConfig) TenantsOrDefault() []TenantProfile {
+	if len(sc.Tenants) > 0 {
+		return sc.Tenants
+	}
+	if len(sc.FairnessWeights) == 0 {
+		return []TenantProfile{{FairnessKey: "", Fairness: 1.0, ArrivalWeight: 1}}
+	}
+	if len(sc.FairnessKeyWeights) == 0 {
+		return []TenantProfile{{FairnessKey: "", Fairness: sc.FairnessWeights[""], ArrivalWeight: 1}}
+	}
+	keys := make([]string, 0, len(sc.FairnessKeyWeights))
+	for key := range sc.FairnessKeyWeights {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	output := make([]TenantProfile, 0, len(keys))
+	for _, key := range keys {
+		output = append(output, TenantProfile{
+			FairnessKey:   key,
+			Fairness:      sc.FairnessWeights[key],
+			ArrivalWeight: sc.FairnessKeyWeights[key],
+			TaskDuration:  sc.TaskDurationByFairnessKey[key],
+		})
+	}
+	return output
+}
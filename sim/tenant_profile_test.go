@@ -0,0 +1,34 @@
+package sim
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_SimulationConfig_TenantsOrDefault(t *testing.T) {
+	slow := ExponentialDuration{Mean: time.Second}
+	sc := SimulationConfig{
+		FairnessKeyWeights:        map[string]int{"high": 100, "low": 500},
+		FairnessWeights:           map[string]float64{"high": 70, "low": 10},
+		TaskDurationByFairnessKey: map[string]DurationDistribution{"low": slow},
+	}
+	tenants := sc.TenantsOrDefault()
+	if len(tenants) != 2 {
+		t.Errorf("expect 2 tenants, was %d", len(tenants))
+		t.FailNow()
+	}
+	if tenants[0].FairnessKey != "high" || tenants[0].ArrivalWeight != 100 || tenants[0].Fairness != 70 || tenants[0].TaskDuration != nil {
+		t.Errorf("expect the first tenant to be translated from the maps, was %+v", tenants[0])
+		t.Fail()
+	}
+	if tenants[1].FairnessKey != "low" || tenants[1].ArrivalWeight != 500 || tenants[1].Fairness != 10 || tenants[1].TaskDuration != slow {
+		t.Errorf("expect the second tenant to be translated from the maps, was %+v", tenants[1])
+		t.Fail()
+	}
+
+	tenants = SimulationConfig{}.TenantsOrDefault()
+	if len(tenants) != 1 || tenants[0].FairnessKey != "" || tenants[0].Fairness != 1.0 {
+		t.Errorf("expect a single default tenant, was %+v", tenants)
+		t.Fail()
+	}
+}
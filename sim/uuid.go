@@ -11,9 +11,26 @@ import (
 var uuidBase uint64 = 1
 
 func NewUUID() UUID {
-	newUID := atomic.AddUint64(&uuidBase, 1)
+	return sequentialUUID(atomic.AddUint64(&uuidBase, 1))
+}
+
+// UUIDSource returns sequential uuids independent of any other source, such that
+// e.g. the task ids of a simulation don't depend on what else ran in the process.
+//
+// The zero value is ready to use.
+type UUIDSource struct {
+	last uint64
+}
+
+// NewUUID returns the next uuid of the source.
+func (us *UUIDSource) NewUUID() UUID {
+	us.last++
+	return sequentialUUID(us.last)
+}
+
+func sequentialUUID(counter uint64) UUID {
 	var uuid UUID
-	binary.PutUvarint(uuid[:], newUID)
+	binary.PutUvarint(uuid[:], counter)
 	uuid[6] = (uuid[6] & 0x0f) | 0x40 // set version 4
 	uuid[8] = (uuid[8] & 0x3f) | 0x80 // set variant 2
 	return uuid
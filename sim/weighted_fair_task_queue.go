@@ -0,0 +1,123 @@
+package sim
+
+// NewWeightedFairTaskQueue returns a new weighted fair queueing (WFQ) task queue.
+//
+// Each pushed task is stamped with a virtual start time, which is the later of the
+// queue's virtual time and the virtual finish time of the previous task with the same
+// fairness key, and a virtual finish time, which is the start time plus the task's
+// estimated cost divided by its fairness weight. Tasks are served in order
+// of smallest virtual finish time (in arrival order within a fairness key), and the
+// queue's virtual time advances to the start time of each task as it is served.
+//
+// The net effect is that each backlogged fairness key receives worker time in proportion to its
+// weight, with a bounded lag of at most one maximum size task per key, independent of how
+// the random draws in the simulation fall.
+//
+// Costs are estimated with the cost model when a task is pushed. When a task completes, the
+// difference between its actual and estimated cost is charged to the finish time of the key's
+// tasks pushed after that.
+func NewWeightedFairTaskQueue(costModel CostModel) TaskQueue {
+	return &weightedFairTaskQueue{
+		costModel:  costModel,
+		lastFinish: make(map[string]float64),
+		lanes:      make(map[string]*Queue[weightedFairItem]),
+		heads:      NewHeap(weightedFairItemLess),
+		inFlight:   make(inFlight),
+	}
+}
+
+type weightedFairTaskQueue struct {
+	costModel   CostModel
+	virtualTime float64
+	lastFinish  map[string]float64
+	seq         uint64
+	len         int
+	// lanes are the tasks of each fairness key in arrival order, and heads the
+	// task at the head of each lane by smallest virtual finish time.
+	lanes map[string]*Queue[weightedFairItem]
+	heads *Heap[weightedFairItem]
+	inFlight
+}
+
+type weightedFairItem struct {
+	task   *Task
+	start  float64
+	finish float64
+	seq    uint64
+}
+
+func weightedFairItemLess(i, j weightedFairItem) bool {
+	if i.finish != j.finish {
+		return i.finish < j.finish
+	}
+	return i.seq < j.seq
+}
+
+func (q *weightedFairTaskQueue) Len() int {
+	return q.len
+}
+
+func (q *weightedFairTaskQueue) Push(t Task) {
+	start := max(q.virtualTime, q.lastFinish[t.FairnessKey])
+	finish := start + q.costModel.Estimate(&t)/fairnessWeightOf(&t)
+	q.lastFinish[t.FairnessKey] = finish
+	q.seq++
+	item := weightedFairItem{
+		task:   &t,
+		start:  start,
+		finish: finish,
+		seq:    q.seq,
+	}
+	lane, ok := q.lanes[t.FairnessKey]
+	if !ok {
+		lane = &Queue[weightedFairItem]{}
+		q.lanes[t.FairnessKey] = lane
+		q.heads.Push(item)
+	}
+	lane.Push(item)
+	q.len++
+}
+
+func (q *weightedFairTaskQueue) Pull() (task *Task, ok bool) {
+	return q.PullFiltered(allowAllFairnessKeys)
+}
+
+// PullFiltered pulls the next task of the fairness keys allow returns true for, as if the
+// tasks of the other fairness keys were not queued.
+func (q *weightedFairTaskQueue) PullFiltered(allow func(fairnessKey string) bool) (task *Task, ok bool) {
+	var skipped []weightedFairItem
+	for q.heads.Len() > 0 {
+		item, _ := q.heads.Pop()
+		if !allow(item.task.FairnessKey) {
+			skipped = append(skipped, item)
+			continue
+		}
+		lane := q.lanes[item.task.FairnessKey]
+		_, _ = lane.Pop()
+		if next, hasNext := lane.Peek(); hasNext {
+			q.heads.Push(next)
+		} else {
+			delete(q.lanes, item.task.FairnessKey)
+		}
+		q.virtualTime = max(q.virtualTime, item.start)
+		q.len--
+		task, ok = item.task, true
+		q.inFlight.dispatched(task)
+		break
+	}
+	for _, item := range skipped {
+		q.heads.Push(item)
+	}
+	return
+}
+
+// OnComplete implements [CompletionObserver].
+func (q *weightedFairTaskQueue) OnComplete(t *Task) {
+	q.inFlight.completed(t)
+	if q.costModel == CostModelTaskCount {
+		return
+	}
+	if _, ok := q.lastFinish[t.FairnessKey]; ok {
+		q.lastFinish[t.FairnessKey] += q.costModel.Correction(t) / fairnessWeightOf(t)
+	}
+}
@@ -0,0 +1,99 @@
+package sim
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_WeightedFairTaskQueue(t *testing.T) {
+	rq := NewWeightedFairTaskQueue(CostModelTaskCount)
+
+	for x := 0; x < 3; x++ {
+		rq.Push(Task{ID: NewUUID(), FairnessKey: "high", Fairness: 2, WorkDuration: time.Second})
+	}
+	for x := 0; x < 3; x++ {
+		rq.Push(Task{ID: NewUUID(), FairnessKey: "low", Fairness: 1, WorkDuration: time.Second})
+	}
+
+	if rq.Len() != 6 {
+		t.Errorf("expect tq length to be 6, was %d", rq.Len())
+		t.Fail()
+	}
+
+	expected := []string{"high", "high", "low", "high", "low", "low"}
+	for x, expectedKey := range expected {
+		task, ok := rq.Pull()
+		if !ok {
+			t.Errorf("expect pull to be ok")
+			t.FailNow()
+		}
+		if task.FairnessKey != expectedKey {
+			t.Errorf("expect pull %d to be from %q, was %q", x, expectedKey, task.FairnessKey)
+			t.Fail()
+		}
+	}
+	if rq.Len() != 0 {
+		t.Errorf("expect tq length to be 0, was %d", rq.Len())
+		t.Fail()
+	}
+}
+
+func Test_WeightedFairTaskQueue_cost(t *testing.T) {
+	rq := NewWeightedFairTaskQueue(CostModelWorkerTime)
+
+	// "slow" tasks are 4x as long, so with equal weights
+	// "fast" should be served 4 times for each "slow".
+	for x := 0; x < 8; x++ {
+		rq.Push(Task{ID: NewUUID(), FairnessKey: "slow", Fairness: 1, WorkDuration: 400 * time.Millisecond})
+		rq.Push(Task{ID: NewUUID(), FairnessKey: "fast", Fairness: 1, WorkDuration: 100 * time.Millisecond})
+	}
+
+	counts := make(map[string]int)
+	for x := 0; x < 5; x++ {
+		task, ok := rq.Pull()
+		if !ok {
+			t.Errorf("expect pull to be ok")
+			t.FailNow()
+		}
+		counts[task.FairnessKey]++
+	}
+	if counts["fast"] != 4 || counts["slow"] != 1 {
+		t.Errorf("expect fast/slow to be 4/1, was %d/%d", counts["fast"], counts["slow"])
+		t.Fail()
+	}
+}
+
+func Test_WeightedFairTaskQueue_correction(t *testing.T) {
+	rq := NewWeightedFairTaskQueue(CostModelWorkerTime)
+	start := time.Date(2024, 01, 01, 12, 00, 00, 00, time.UTC)
+
+	// both keys are estimated to cost 1s a task, but "slow" tasks actually take 3s.
+	for _, key := range []string{"slow", "fast"} {
+		rq.Push(Task{ID: NewUUID(), FairnessKey: key, Fairness: 1, WorkDuration: time.Second})
+		task, _ := rq.Pull()
+		task.DispatchedUTC = start
+		task.CompletedUTC = start.Add(time.Second)
+		if key == "slow" {
+			task.CompletedUTC = start.Add(3 * time.Second)
+		}
+		rq.(CompletionObserver).OnComplete(task)
+	}
+
+	// "slow" was charged the 2s it ran over, so "fast" is served for 2s before "slow" is served again.
+	for x := 0; x < 4; x++ {
+		rq.Push(Task{ID: NewUUID(), FairnessKey: "slow", Fairness: 1, WorkDuration: time.Second})
+		rq.Push(Task{ID: NewUUID(), FairnessKey: "fast", Fairness: 1, WorkDuration: time.Second})
+	}
+	expected := []string{"fast", "fast", "slow", "fast"}
+	for x, expectedKey := range expected {
+		task, ok := rq.Pull()
+		if !ok {
+			t.Errorf("expect pull to be ok")
+			t.FailNow()
+		}
+		if task.FairnessKey != expectedKey {
+			t.Errorf("expect pull %d to be from %q, was %q", x, expectedKey, task.FairnessKey)
+			t.Fail()
+		}
+	}
+}
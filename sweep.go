@@ -0,0 +1,141 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"queue_fairness/sim"
+)
+
+// sweepParameters are the parameters given with (repeated) --param flags.
+type sweepParameters []sim.SweepParameter
+
+func (sp *sweepParameters) String() string {
+	var names []string
+	for _, param := range *sp {
+		names = append(names, param.Name)
+	}
+	return strings.Join(names, ",")
+}
+
+func (sp *sweepParameters) Set(value string) error {
+	param, err := sim.ParseSweepParameter(value)
+	if err != nil {
+		return err
+	}
+	*sp = append(*sp, param)
+	return nil
+}
+
+var flagSweepParameters sweepParameters
+
+func init() {
+	flag.Var(&flagSweepParameters, "param", "a parameter to sweep, e.g. tasksPerSecond=2000..4000:200 or workerCount=16,32,64; given once or twice (sweep only)")
+}
+
+// sweep runs the scenario at each point of the grid of the swept parameters
+// and writes the queueing delay by fairness key at each point.
+func sweep() {
+	if len(flagSweepParameters) == 0 || len(flagSweepParameters) > 2 {
+		fmt.Fprintf(os.Stderr, "sweep takes one or two --param flags with one of %s\n", strings.Join(sim.SweepParameters, ", "))
+		os.Exit(1)
+	}
+	writeSweep, err := sweepWriter(*flagOutputFormat)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+	var info io.Writer = os.Stdout
+	if *flagOutputFormat != "text" {
+		info = os.Stderr
+	}
+
+	scenario, err := loadScenario("")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+	points := 1
+	for _, param := range flagSweepParameters {
+		points *= len(param.Values)
+		fmt.Fprintf(info, "sweeping %s over %s\n", param.Name, strings.Join(param.Values, ", "))
+	}
+	fmt.Fprintf(info, "simulating %d points with seed %d\n", points, *scenario.Seed)
+
+	sim.LogOutput = io.Discard
+	start := time.Now()
+	res, err := sim.Sweep(scenario, flagSweepParameters, *flagParallelism)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+	fmt.Fprintf(info, "simulations complete! %v elapsed\n", time.Since(start).Round(time.Millisecond).String())
+	fmt.Fprintln(info)
+
+	output := os.Stdout
+	if *flagOutputFile != "" {
+		output, err = os.Create(*flagOutputFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+	}
+	if err = writeSweep(output, res); err == nil && output != os.Stdout {
+		err = output.Close()
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error writing results: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// sweepWriter returns the function that writes sweep results in a given output format.
+func sweepWriter(format string) (func(io.Writer, sim.SweepResults) error, error) {
+	switch format {
+	case "text":
+		return printSweep, nil
+	case "json":
+		return sim.WriteSweepJSON, nil
+	case "csv":
+		return sim.WriteSweepCSV, nil
+	default:
+		return nil, fmt.Errorf("invalid output format: %v", format)
+	}
+}
+
+// printSweep writes the queued p95 at each point of the sweep as a table with a column per fairness key.
+func printSweep(w io.Writer, res sim.SweepResults) error {
+	fairnessKeys := make(map[string]struct{})
+	for _, point := range res.Points {
+		for key := range point.Results.QueuedP95ByFairnessKey {
+			fairnessKeys[key] = struct{}{}
+		}
+	}
+	keys := sortedKeys(fairnessKeys)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	for _, param := range res.Parameters {
+		fmt.Fprintf(tw, "%s\t", param.Name)
+	}
+	fmt.Fprint(tw, "tasks processed\tqueued p95\t")
+	for _, key := range keys {
+		fmt.Fprintf(tw, "%q p95\t", key)
+	}
+	fmt.Fprintln(tw)
+	for _, point := range res.Points {
+		for _, value := range point.Values {
+			fmt.Fprintf(tw, "%s\t", value)
+		}
+		fmt.Fprintf(tw, "%d\t%v\t", point.Results.TasksProcessed, point.Results.QueuedP95.Round(time.Millisecond))
+		for _, key := range keys {
+			fmt.Fprintf(tw, "%v\t", point.Results.QueuedP95ByFairnessKey[key].Round(time.Millisecond))
+		}
+		fmt.Fprintln(tw)
+	}
+	return tw.Flush()
+}